@@ -1,38 +1,142 @@
 package main
 
 import (
+	"bytes"
+	"container/list"
+	"context"
+	"encoding/binary"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math"
+	"math/rand"
+	"net"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 )
 
-// parallel http requests test using goroutines
-func parallelHttpTest(numRequests int) float64 {
+// startTestServer serves /fast in-process, like the mock server.py does,
+// after waiting latency to stand in for real handler work, POSTs get their
+// body read and its size echoed back
+func startTestServer(latency time.Duration) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/fast", func(w http.ResponseWriter, r *http.Request) {
+		if latency > 0 {
+			time.Sleep(latency)
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		if r.Method == http.MethodPost {
+			n, _ := io.Copy(ioutil.Discard, r.Body)
+			fmt.Fprintf(w, "Received %d bytes", n)
+			return
+		}
+		w.Write([]byte("Fast response"))
+	})
+	return httptest.NewServer(mux)
+}
+
+// httpLoadConfig shapes the http test's load
+type httpLoadConfig struct {
+	requests     int
+	concurrency  int     // requests in flight at once, 0 means all of them
+	postRatio    float64 // share of requests sent as POST
+	payloadBytes int     // POST body size
+	keepAlive    bool    // reuse connections, or dial one per request
+}
+
+// firstError keeps the first error reported by any goroutine, atomic.Value
+// can't do this because it panics once two different error types are stored
+type firstError struct {
+	mu  sync.Mutex
+	err error
+}
+
+func (f *firstError) set(err error) {
+	f.mu.Lock()
+	if f.err == nil {
+		f.err = err
+	}
+	f.mu.Unlock()
+}
+
+func (f *firstError) get() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.err
+}
+
+// parallel http requests test using goroutines, a fixed number of workers
+// work through the requests and each request's latency is kept
+func parallelHttpTest(cfg httpLoadConfig, targetURL string) (float64, error) {
+	workers := cfg.concurrency
+	if workers <= 0 || workers > cfg.requests {
+		workers = cfg.requests
+	}
+	transport := &http.Transport{
+		MaxIdleConnsPerHost: workers,
+		DisableKeepAlives:   !cfg.keepAlive,
+	}
+	defer transport.CloseIdleConnections()
+	client := &http.Client{
+		Timeout:   5 * time.Second,
+		Transport: transport,
+	}
+	payload := bytes.Repeat([]byte("x"), cfg.payloadBytes)
+
+	latencies := make([]time.Duration, cfg.requests)
+	posts := make([]bool, cfg.requests)
+	for i := range posts {
+		// spread POSTs evenly instead of randomly so runs are comparable
+		posts[i] = int(float64(i+1)*cfg.postRatio) > int(float64(i)*cfg.postRatio)
+	}
+
 	start := time.Now()
 
 	var wg sync.WaitGroup
-	var successful int32
+	var next, successful int32
+	var firstErr firstError
 
-	for i := 0; i < numRequests; i++ {
+	for w := 0; w < workers; w++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
+			for {
+				i := int(atomic.AddInt32(&next, 1)) - 1
+				if i >= cfg.requests {
+					return
+				}
 
-			client := &http.Client{
-				Timeout: 5 * time.Second,
-			}
-
-			resp, err := client.Get("http://127.0.0.1:8000/fast")
-			if err == nil {
+				requestStart := time.Now()
+				var resp *http.Response
+				var err error
+				if posts[i] {
+					resp, err = client.Post(targetURL, "application/octet-stream", bytes.NewReader(payload))
+				} else {
+					resp, err = client.Get(targetURL)
+				}
+				if err != nil {
+					firstErr.set(err)
+					continue
+				}
 				io.Copy(ioutil.Discard, resp.Body)
 				resp.Body.Close()
+				if resp.StatusCode != http.StatusOK {
+					firstErr.set(fmt.Errorf("unexpected status -> %s", resp.Status))
+					continue
+				}
+				latencies[i] = time.Since(requestStart)
 				atomic.AddInt32(&successful, 1)
 			}
 		}()
@@ -41,8 +145,31 @@ func parallelHttpTest(numRequests int) float64 {
 	wg.Wait()
 
 	duration := time.Since(start)
-	_ = atomic.LoadInt32(&successful) // prevent optimization
-	return float64(duration.Nanoseconds()) / 1000000.0
+	ok := int(atomic.LoadInt32(&successful))
+	if ok == 0 {
+		return 0.0, fmt.Errorf("no request to %s succeeded: %v", targetURL, firstErr.get())
+	}
+	if ok < cfg.requests {
+		fmt.Fprintf(os.Stderr, "parallel http: only %d of %d requests succeeded, first error -> %v\n", ok, cfg.requests, firstErr.get())
+	}
+
+	// failed requests left a zero latency behind, drop them
+	sort.Slice(latencies, func(a, b int) bool { return latencies[a] < latencies[b] })
+	latencies = latencies[cfg.requests-ok:]
+	percentile := func(p float64) float64 {
+		return float64(latencies[int(p*float64(len(latencies)-1))].Microseconds()) / 1000.0
+	}
+	postCount := 0
+	for _, post := range posts {
+		if post {
+			postCount++
+		}
+	}
+	fmt.Fprintf(os.Stderr, "parallel http: %d requests (%d POST x %dB), %d in flight, keep-alive %t, %.0f req/s\n",
+		cfg.requests, postCount, cfg.payloadBytes, workers, cfg.keepAlive, float64(ok)/duration.Seconds())
+	fmt.Fprintf(os.Stderr, "parallel http latency: p50 %.3f ms, p90 %.3f ms, p99 %.3f ms, max %.3f ms\n",
+		percentile(0.50), percentile(0.90), percentile(0.99), percentile(1))
+	return float64(duration.Nanoseconds()) / 1000000.0, nil
 }
 
 // producer-consumer queue test using channels
@@ -89,6 +216,88 @@ func producerConsumerTest(numPairs int, itemsPerThread int) float64 {
 	return float64(duration.Nanoseconds()) / 1000000.0
 }
 
+// itemBatch carries when its first item was produced along with the items
+type itemBatch struct {
+	items []int
+	first time.Time
+}
+
+// batched channel test is producerConsumerTest sending slices of batchSize
+// items per channel operation instead of single ints, with the same number
+// of pairs and the channel buffer holding the same number of items. each
+// item costs a little work to produce, so a bigger batch also means the
+// first item in it waits longer before anyone can consume it
+func batchedChannelTest(numPairs int, itemsPerProducer int, batchSizes []int) float64 {
+	const bufferedItems = 1000
+
+	totalTime := 0.0
+	for _, batchSize := range batchSizes {
+		queue := make(chan itemBatch, max(bufferedItems/batchSize, 1))
+		var processed, checksum atomic.Int64
+		latencies := make([][]time.Duration, numPairs)
+
+		start := time.Now()
+		var producers, consumers sync.WaitGroup
+		for p := 0; p < numPairs; p++ {
+			producers.Add(1)
+			go func() {
+				defer producers.Done()
+				batch := itemBatch{items: make([]int, 0, batchSize)}
+				var work int64
+				for j := 0; j < itemsPerProducer; j++ {
+					if len(batch.items) == 0 {
+						batch.first = time.Now()
+					}
+					work += spinWork(1)
+					batch.items = append(batch.items, p*itemsPerProducer+j)
+					if len(batch.items) == batchSize || j == itemsPerProducer-1 {
+						queue <- batch
+						batch = itemBatch{items: make([]int, 0, batchSize)}
+					}
+				}
+				checksum.Add(work)
+			}()
+		}
+		for c := 0; c < numPairs; c++ {
+			consumers.Add(1)
+			go func() {
+				defer consumers.Done()
+				var sum, count int64
+				for batch := range queue {
+					latencies[c] = append(latencies[c], time.Since(batch.first))
+					for _, item := range batch.items {
+						// simulate processing
+						sum += int64(item * item)
+					}
+					count += int64(len(batch.items))
+				}
+				processed.Add(count)
+				checksum.Add(sum)
+			}()
+		}
+		producers.Wait()
+		close(queue)
+		consumers.Wait()
+		duration := time.Since(start)
+		totalTime += float64(duration.Nanoseconds()) / 1000000.0
+
+		var all []time.Duration
+		for _, l := range latencies {
+			all = append(all, l...)
+		}
+		sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+		items := numPairs * itemsPerProducer
+		if processed.Load() != int64(items) {
+			fmt.Fprintf(os.Stderr, "batched channel batch %d processed %d of %d items\n", batchSize, processed.Load(), items)
+		}
+		_ = checksum.Load() // prevent optimization
+		fmt.Fprintf(os.Stderr, "batched channel batch %5d: %d pairs, %.2f M items/s, %d channel ops, first item latency p50 %.1f us, p99 %.1f us\n",
+			batchSize, numPairs, float64(items)/duration.Seconds()/1e6, len(all),
+			float64(all[len(all)/2].Nanoseconds())/1000.0, float64(all[int(0.99*float64(len(all)-1))].Nanoseconds())/1000.0)
+	}
+	return totalTime
+}
+
 // fibonacci computation
 func fibonacci(n int) int64 {
 	if n <= 1 {
@@ -135,75 +344,302 @@ func parallelMathTest(numThreads int, workPerThread int) float64 {
 	return float64(duration.Nanoseconds()) / 1000000.0
 }
 
-// async file processing test
-func asyncFileTest(numFiles int) float64 {
-	start := time.Now()
+// os thread test runs the parallel math workload split over workers that
+// either float freely or call runtime.LockOSThread first, at worker counts
+// from the core count up to well past it, then ping-pongs a value between
+// goroutine pairs over unbuffered channels. locked goroutines can only run
+// on their own thread, so every handoff there is an OS thread switch, which
+// is the cost M:N scheduling saves
+func osThreadTest(workerCounts []int, totalUnits int, pairs int, roundTrips int) float64 {
+	threads := pprof.Lookup("threadcreate")
+	totalTime := 0.0
 
-	tempDir, err := ioutil.TempDir("", "concurrency_test")
+	for _, locked := range []bool{false, true} {
+		mode := "free goroutines"
+		if locked {
+			mode = "LockOSThread"
+		}
+		for _, workers := range workerCounts {
+			threadsBefore := threads.Count()
+			var checksum atomic.Int64
+			start := time.Now()
+			var wg sync.WaitGroup
+			for w := 0; w < workers; w++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if locked {
+						runtime.LockOSThread()
+						defer runtime.UnlockOSThread()
+					}
+					var sum int64
+					for u := w; u < totalUnits; u += workers {
+						sum += mathWorkUnit()
+					}
+					checksum.Add(sum)
+				}()
+			}
+			wg.Wait()
+			duration := time.Since(start)
+			totalTime += float64(duration.Nanoseconds()) / 1000000.0
+			fmt.Fprintf(os.Stderr, "os threads %-16s math %4d workers on %d cpus: %.3f ms, %d new threads\n",
+				mode, workers, runtime.NumCPU(), float64(duration.Nanoseconds())/1000000.0, threads.Count()-threadsBefore)
+		}
+
+		start := time.Now()
+		var wg sync.WaitGroup
+		for p := 0; p < pairs; p++ {
+			ping, pong := make(chan int), make(chan int)
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				if locked {
+					runtime.LockOSThread()
+					defer runtime.UnlockOSThread()
+				}
+				for i := 0; i < roundTrips; i++ {
+					ping <- i
+					<-pong
+				}
+				close(ping)
+			}()
+			go func() {
+				defer wg.Done()
+				if locked {
+					runtime.LockOSThread()
+					defer runtime.UnlockOSThread()
+				}
+				for v := range ping {
+					pong <- v
+				}
+			}()
+		}
+		wg.Wait()
+		duration := time.Since(start)
+		totalTime += float64(duration.Nanoseconds()) / 1000000.0
+		fmt.Fprintf(os.Stderr, "os threads %-16s ping-pong %d pairs: %.0f ns/round trip\n",
+			mode, pairs, float64(duration.Nanoseconds())/float64(pairs*roundTrips))
+	}
+	return totalTime
+}
+
+// asyncFile writes a file of lines, reads it back, counts the lines and
+// removes it, the unit of work every model in asyncFileTest runs
+func asyncFile(dir string, fileID int) error {
+	filename := filepath.Join(dir, fmt.Sprintf("test_%d.dat", fileID))
+
+	// write file
+	file, err := os.Create(filename)
 	if err != nil {
-		return 0.0
+		return err
 	}
-	defer os.RemoveAll(tempDir)
 
-	var wg sync.WaitGroup
-	var processed int32
+	for j := 0; j < 1000; j++ {
+		fmt.Fprintf(file, "data_%d_%d\n", fileID, j)
+	}
+	file.Close()
 
-	for i := 0; i < numFiles; i++ {
-		wg.Add(1)
-		go func(fileID int) {
-			defer wg.Done()
+	// read and process file
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
 
-			filename := filepath.Join(tempDir, fmt.Sprintf("test_%d.dat", fileID))
+	// simulate processing
+	lines := 0
+	for _, b := range content {
+		if b == '\n' {
+			lines++
+		}
+	}
+	if lines != 1000 {
+		return fmt.Errorf("%s has %d lines, want 1000", filename, lines)
+	}
 
-			// write file
-			file, err := os.Create(filename)
-			if err != nil {
-				return
-			}
+	// cleanup
+	return os.Remove(filename)
+}
 
-			for j := 0; j < 1000; j++ {
-				fmt.Fprintf(file, "data_%d_%d\n", fileID, j)
-			}
-			file.Close()
+// async file test runs the same file workload under each concurrency model,
+// a goroutine per file, a WorkerPool of workers and every task group limited
+// to workers, so the models can be compared instead of picking one
+func asyncFileTest(numFiles int, workers int) float64 {
+	tempDir, err := ioutil.TempDir("", "concurrency_test")
+	if err != nil {
+		return 0.0
+	}
+	defer os.RemoveAll(tempDir)
 
-			// read and process file
-			content, err := ioutil.ReadFile(filename)
-			if err != nil {
-				return
+	type fileModel struct {
+		name string
+		run  func(job func(fileID int) error)
+	}
+	models := []fileModel{
+		{"goroutine per file", func(job func(int) error) {
+			var wg sync.WaitGroup
+			for i := 0; i < numFiles; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					job(i)
+				}()
+			}
+			wg.Wait()
+		}},
+		{fmt.Sprintf("worker pool of %d", workers), func(job func(int) error) {
+			pool := NewWorkerPool(workers)
+			for i := 0; i < numFiles; i++ {
+				pool.Submit(context.Background(), func() { job(i) })
+			}
+			pool.Wait()
+			pool.Close()
+		}},
+	}
+	for _, impl := range groupImpls {
+		models = append(models, fileModel{fmt.Sprintf("%s limit %d", impl.name, workers), func(job func(int) error) {
+			g, _ := impl.new(context.Background(), workers)
+			for i := 0; i < numFiles; i++ {
+				g.Go(func() error { return job(i) })
 			}
+			g.Wait()
+		}})
+	}
 
-			// simulate processing
-			lines := 0
-			for _, b := range content {
-				if b == '\n' {
-					lines++
-				}
+	// only the first model, a goroutine per file, is what the other
+	// languages do, the rest are reported on stderr for comparison
+	totalTime := 0.0
+	for m, model := range models {
+		var processed, failed atomic.Int32
+		var firstErr firstError
+		start := time.Now()
+		model.run(func(fileID int) error {
+			if err := asyncFile(tempDir, fileID); err != nil {
+				failed.Add(1)
+				firstErr.set(err)
+				return err
 			}
+			processed.Add(1)
+			return nil
+		})
+		duration := time.Since(start)
+		if m == 0 {
+			totalTime = float64(duration.Nanoseconds()) / 1000000.0
+		}
 
-			if lines > 0 {
-				atomic.AddInt32(&processed, 1)
+		fmt.Fprintf(os.Stderr, "async file %-28s %d files, %.3f ms, %.0f files/s\n",
+			model.name, numFiles, float64(duration.Nanoseconds())/1000000.0, float64(processed.Load())/duration.Seconds())
+		if failed.Load() > 0 {
+			fmt.Fprintf(os.Stderr, "  %d files failed, first error -> %v\n", failed.Load(), firstErr.get())
+		}
+	}
+	return totalTime
+}
+
+// mixed workload test feeds cpu tasks (repeated fibonacci), file reads and
+// short sleeps through one WorkerPool, first each class on its own and then
+// all three interleaved, and reports how much each class's latency from
+// submit to completion grows once it shares the workers with the others
+func mixedWorkloadTest(workers int, tasksPerClass int) float64 {
+	tempDir, err := ioutil.TempDir("", "concurrency_mixed")
+	if err != nil {
+		return 0.0
+	}
+	defer os.RemoveAll(tempDir)
+	readPath := filepath.Join(tempDir, "read.dat")
+	if err := os.WriteFile(readPath, bytes.Repeat([]byte("0123456789abcdef"), 4096), 0644); err != nil {
+		return 0.0
+	}
+
+	var sink atomic.Int64
+	classes := []struct {
+		name string
+		task func()
+	}{
+		{"cpu", func() {
+			var sum int64
+			for k := 0; k < 2000; k++ {
+				sum += fibonacci(90)
+			}
+			sink.Add(sum)
+		}},
+		{"file read", func() {
+			content, err := os.ReadFile(readPath)
+			if err == nil {
+				sink.Add(int64(len(content)))
 			}
+		}},
+		{"sleep 1ms", func() { time.Sleep(time.Millisecond) }},
+	}
 
-			// cleanup
-			os.Remove(filename)
-		}(i)
+	// run submits the tasks in order and returns each class's latencies. only
+	// two per worker are in flight at once, so the latency shows the classes
+	// getting in each other's way rather than a long queue
+	run := func(order []int) [][]time.Duration {
+		latencies := make([][]time.Duration, len(classes))
+		var mu sync.Mutex
+		inFlight := make(chan struct{}, 2*workers)
+		pool := NewWorkerPool(workers)
+		for _, class := range order {
+			inFlight <- struct{}{}
+			submitted := time.Now()
+			pool.Submit(context.Background(), func() {
+				defer func() { <-inFlight }()
+				classes[class].task()
+				latency := time.Since(submitted)
+				mu.Lock()
+				latencies[class] = append(latencies[class], latency)
+				mu.Unlock()
+			})
+		}
+		pool.Wait()
+		pool.Close()
+		return latencies
+	}
+	percentiles := func(l []time.Duration) (float64, float64) {
+		sort.Slice(l, func(i, j int) bool { return l[i] < l[j] })
+		return float64(l[len(l)/2].Microseconds()) / 1000.0, float64(l[int(0.99*float64(len(l)-1))].Microseconds()) / 1000.0
 	}
 
-	wg.Wait()
+	start := time.Now()
+	alone := make([][]time.Duration, len(classes))
+	for c := range classes {
+		order := make([]int, tasksPerClass)
+		for i := range order {
+			order[i] = c
+		}
+		alone[c] = run(order)[c]
+	}
 
+	order := make([]int, 0, len(classes)*tasksPerClass)
+	for i := 0; i < tasksPerClass; i++ {
+		for c := range classes {
+			order = append(order, c)
+		}
+	}
+	mixed := run(order)
 	duration := time.Since(start)
-	_ = atomic.LoadInt32(&processed) // prevent optimization
+
+	fmt.Fprintf(os.Stderr, "mixed workload, %d workers, %d tasks per class, submit to done latency:\n", workers, tasksPerClass)
+	for c, class := range classes {
+		aloneP50, aloneP99 := percentiles(alone[c])
+		mixedP50, mixedP99 := percentiles(mixed[c])
+		fmt.Fprintf(os.Stderr, "  %-10s alone p50 %8.3f ms p99 %8.3f ms, mixed p50 %8.3f ms p99 %8.3f ms (p99 %.1fx)\n",
+			class.name, aloneP50, aloneP99, mixedP50, mixedP99, mixedP99/max(aloneP99, 0.001))
+	}
+	_ = sink.Load() // prevent optimization
 	return float64(duration.Nanoseconds()) / 1000000.0
 }
 
-// worker pool structure
-type WorkerPool struct {
+// legacyWorkerPool is the original pool, kept so threadPoolTest can compare
+// against it. Close racing a Submit panics on the closed channel and a
+// panicking task takes the whole process down
+type legacyWorkerPool struct {
 	taskQueue chan func()
 	wg        sync.WaitGroup
 }
 
-func NewWorkerPool(numWorkers int) *WorkerPool {
-	pool := &WorkerPool{
+func newLegacyWorkerPool(numWorkers int) *legacyWorkerPool {
+	pool := &legacyWorkerPool{
 		taskQueue: make(chan func(), 100),
 	}
 
@@ -219,7 +655,7 @@ func NewWorkerPool(numWorkers int) *WorkerPool {
 	return pool
 }
 
-func (p *WorkerPool) Submit(task func()) {
+func (p *legacyWorkerPool) Submit(task func()) {
 	p.wg.Add(1)
 	p.taskQueue <- func() {
 		defer p.wg.Done()
@@ -227,64 +663,3053 @@ func (p *WorkerPool) Submit(task func()) {
 	}
 }
 
-func (p *WorkerPool) Wait() {
+func (p *legacyWorkerPool) Wait() {
 	p.wg.Wait()
 }
 
-func (p *WorkerPool) Close() {
+func (p *legacyWorkerPool) Close() {
 	close(p.taskQueue)
 }
 
-// thread pool performance test
+var (
+	ErrPoolClosed = errors.New("worker pool is closed")
+	ErrPoolFull   = errors.New("worker pool queue is full")
+)
+
+// PoolOptions configures a WorkerPool. QueueSize bounds how many tasks can
+// wait for a worker, Block picks whether Submit waits for room or rejects
+type PoolOptions struct {
+	Workers   int
+	QueueSize int
+	Block     bool
+}
+
+// PoolStats counts what happened to the tasks a pool accepted or turned away
+type PoolStats struct {
+	Completed int64
+	Panicked  int64
+	Rejected  int64
+}
+
+// worker pool with a bounded queue. the read lock is held for the whole send
+// so Close can't close the queue under a Submit, and Close drains whatever
+// was accepted before returning
+type WorkerPool struct {
+	tasks   chan func()
+	block   bool
+	mu      sync.RWMutex
+	closed  bool
+	pending sync.WaitGroup
+	workers sync.WaitGroup
+
+	completed atomic.Int64
+	panicked  atomic.Int64
+	rejected  atomic.Int64
+}
+
+func NewWorkerPool(numWorkers int) *WorkerPool {
+	return NewWorkerPoolWithOptions(PoolOptions{Workers: numWorkers, QueueSize: 100, Block: true})
+}
+
+func NewWorkerPoolWithOptions(opts PoolOptions) *WorkerPool {
+	pool := &WorkerPool{
+		tasks: make(chan func(), max(opts.QueueSize, 0)),
+		block: opts.Block,
+	}
+
+	workers := max(opts.Workers, 1)
+	pool.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer pool.workers.Done()
+			for task := range pool.tasks {
+				pool.run(task)
+			}
+		}()
+	}
+
+	return pool
+}
+
+// run executes one task, a panic is counted instead of killing the worker
+func (p *WorkerPool) run(task func()) {
+	defer p.pending.Done()
+	defer func() {
+		if r := recover(); r != nil {
+			p.panicked.Add(1)
+			return
+		}
+		p.completed.Add(1)
+	}()
+	task()
+}
+
+// Submit queues task. a blocking pool waits for room until ctx is done, a
+// non-blocking one returns ErrPoolFull straight away
+func (p *WorkerPool) Submit(ctx context.Context, task func()) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		p.rejected.Add(1)
+		return ErrPoolClosed
+	}
+
+	p.pending.Add(1)
+	if p.block {
+		select {
+		case p.tasks <- task:
+			return nil
+		case <-ctx.Done():
+			p.pending.Done()
+			p.rejected.Add(1)
+			return ctx.Err()
+		}
+	}
+
+	select {
+	case p.tasks <- task:
+		return nil
+	default:
+		p.pending.Done()
+		p.rejected.Add(1)
+		return ErrPoolFull
+	}
+}
+
+// Wait blocks until every accepted task has finished
+func (p *WorkerPool) Wait() {
+	p.pending.Wait()
+}
+
+// Close stops new submissions, lets the workers drain the queue and waits
+// for them to exit. calling it more than once is fine
+func (p *WorkerPool) Close() {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.tasks)
+	}
+	p.mu.Unlock()
+	p.workers.Wait()
+}
+
+func (p *WorkerPool) Stats() PoolStats {
+	return PoolStats{
+		Completed: p.completed.Load(),
+		Panicked:  p.panicked.Load(),
+		Rejected:  p.rejected.Load(),
+	}
+}
+
+// thread pool performance test runs the same workload through the legacy
+// pool and the bounded one, then pushes a burst with some panicking tasks
+// into a small rejecting pool to check the counts add up. only the two
+// comparable runs go into the total
 func threadPoolTest(poolSize int, totalTasks int) float64 {
+	var completed int32
+	work := func() {
+		// simulate varied workload
+		var work int64
+		for j := 0; j < 10000; j++ {
+			work += int64(j * j)
+		}
+
+		time.Sleep(100 * time.Microsecond)
+		atomic.AddInt32(&completed, 1)
+
+		_ = work // prevent optimization
+	}
+
 	start := time.Now()
+	legacy := newLegacyWorkerPool(poolSize)
+	for i := 0; i < totalTasks; i++ {
+		legacy.Submit(work)
+	}
+	legacy.Wait()
+	legacy.Close()
+	legacyTime := time.Since(start)
 
+	ctx := context.Background()
+	start = time.Now()
 	pool := NewWorkerPool(poolSize)
-	defer pool.Close()
+	for i := 0; i < totalTasks; i++ {
+		if err := pool.Submit(ctx, work); err != nil {
+			fmt.Fprintf(os.Stderr, "Thread pool submit failed -> %v\n", err)
+		}
+	}
+	pool.Wait()
+	pool.Close()
+	boundedTime := time.Since(start)
 
-	var completed int32
+	fmt.Fprintf(os.Stderr, "Thread pool (%d workers, %d tasks): legacy %.3f ms, bounded %.3f ms (%.2fx)\n",
+		poolSize, totalTasks, float64(legacyTime.Nanoseconds())/1000000.0,
+		float64(boundedTime.Nanoseconds())/1000000.0, float64(boundedTime)/float64(max(legacyTime, 1)))
 
+	// every 50th task panics and the queue only holds 16, so a tight submit
+	// loop gets some rejected, accepted tasks must land as completed or panicked
+	rejecting := NewWorkerPoolWithOptions(PoolOptions{Workers: poolSize, QueueSize: 16})
 	for i := 0; i < totalTasks; i++ {
-		taskID := i
-		pool.Submit(func() {
-			// simulate varied workload
-			var work int64
-			for j := 0; j < 10000; j++ {
-				work += int64(j * j)
+		rejecting.Submit(ctx, func() {
+			if i%50 == 0 {
+				panic("injected task panic")
 			}
+			work()
+		})
+	}
+	rejecting.Close()
+	if err := rejecting.Submit(ctx, work); !errors.Is(err, ErrPoolClosed) {
+		fmt.Fprintf(os.Stderr, "  submit after close returned %v, want %v\n", err, ErrPoolClosed)
+	}
+	stats := rejecting.Stats()
+	fmt.Fprintf(os.Stderr, "  rejecting pool (queue 16): completed %d, panicked %d, rejected %d\n",
+		stats.Completed, stats.Panicked, stats.Rejected)
+	if stats.Completed+stats.Panicked+stats.Rejected != int64(totalTasks)+1 {
+		fmt.Fprintf(os.Stderr, "  rejecting pool lost tasks: %d submitted\n", totalTasks+1)
+	}
 
-			time.Sleep(100 * time.Microsecond)
-			atomic.AddInt32(&completed, 1)
+	// the legacy pool is only there for comparison, the bounded pool is the
+	// thread pool number the other languages are compared against
+	_ = atomic.LoadInt32(&completed) // prevent optimization
+	return float64(boundedTime.Nanoseconds()) / 1000000.0
+}
 
-			_ = work // prevent optimization
-		})
+// channel sweep test pushes the same number of messages through a channel at
+// each buffer size, with one producer and one consumer and with several
+// of each, since the unbuffered and tiny buffer cases hand off very differently
+func channelSweepTest(messages int, mpmcWorkers int) float64 {
+	buffers := []int{0, 1, 64, 1024, 64 * 1024}
+	shapes := []struct {
+		name                 string
+		producers, consumers int
+	}{
+		{"spsc", 1, 1},
+		{fmt.Sprintf("mpmc %dx%d", mpmcWorkers, mpmcWorkers), mpmcWorkers, mpmcWorkers},
 	}
 
-	pool.Wait()
+	totalTime := 0.0
+	fmt.Fprintf(os.Stderr, "%-12s", "M msgs/s")
+	for _, buffer := range buffers {
+		label := fmt.Sprintf("buf %d", buffer)
+		if buffer >= 1024 {
+			label = fmt.Sprintf("buf %dK", buffer/1024)
+		}
+		fmt.Fprintf(os.Stderr, " %8s", label)
+	}
+	fmt.Fprintln(os.Stderr)
 
-	duration := time.Since(start)
-	_ = atomic.LoadInt32(&completed) // prevent optimization
-	return float64(duration.Nanoseconds()) / 1000000.0
+	for _, shape := range shapes {
+		fmt.Fprintf(os.Stderr, "%-12s", shape.name)
+		for _, buffer := range buffers {
+			start := time.Now()
+
+			ch := make(chan int, buffer)
+			var producers, consumers sync.WaitGroup
+			var received int64
+			perProducer := messages / shape.producers
+			for p := 0; p < shape.producers; p++ {
+				producers.Add(1)
+				go func() {
+					defer producers.Done()
+					for i := 0; i < perProducer; i++ {
+						ch <- i
+					}
+				}()
+			}
+			for c := 0; c < shape.consumers; c++ {
+				consumers.Add(1)
+				go func() {
+					defer consumers.Done()
+					var count int64
+					for range ch {
+						count++
+					}
+					atomic.AddInt64(&received, count)
+				}()
+			}
+			producers.Wait()
+			close(ch)
+			consumers.Wait()
+
+			duration := time.Since(start)
+			totalTime += float64(duration.Nanoseconds()) / 1000000.0
+			fmt.Fprintf(os.Stderr, " %8.2f", float64(atomic.LoadInt64(&received))/duration.Seconds()/1e6)
+		}
+		fmt.Fprintln(os.Stderr)
+	}
+	return totalTime
 }
 
-func main() {
-	scaleFactor := 1
+//go:generate go run concurrency_select_gen.go
 
-	if len(os.Args) > 1 {
-		if factor, err := strconv.Atoi(os.Args[1]); err == nil && factor > 0 {
-			scaleFactor = factor
-		} else {
-			fmt.Fprintf(os.Stderr, "Invalid scale factor. Using default 1.\n")
+// fanIn has one consumer select over n producer channels until all of them
+// are closed, the selects come from concurrency_select.go so each n is a
+// real select statement with n cases
+func fanIn(n int, messages int) time.Duration {
+	recv := selectRecvN[n]
+	inputs := make([]chan int, n)
+	for i := range inputs {
+		inputs[i] = make(chan int, 64)
+	}
+	// the select reads from cases, closed channels are swapped for nil there
+	cases := append([]chan int(nil), inputs...)
+
+	start := time.Now()
+	for i, in := range inputs {
+		go func() {
+			for j := 0; j < messages/n; j++ {
+				in <- i + j
+			}
+			close(in)
+		}()
+	}
+
+	var sum int64
+	for open := n; open > 0; {
+		chosen, value, ok := recv(cases)
+		if !ok {
+			// a nil channel is never ready, so select skips it from now on
+			cases[chosen] = nil
+			open--
+			continue
 		}
+		sum += int64(value)
+	}
+	_ = sum // prevent optimization
+	return time.Since(start)
+}
+
+// fanOut is the reverse, one producer selecting over n consumer channels and
+// sending to whichever has room
+func fanOut(n int, messages int) time.Duration {
+	send := selectSendN[n]
+	outputs := make([]chan int, n)
+	for i := range outputs {
+		outputs[i] = make(chan int, 64)
 	}
 
+	start := time.Now()
+	var wg sync.WaitGroup
+	var received int64
+	for _, out := range outputs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var count int64
+			for range out {
+				count++
+			}
+			atomic.AddInt64(&received, count)
+		}()
+	}
+
+	for i := 0; i < messages/n*n; i++ {
+		send(outputs, i)
+	}
+	for _, out := range outputs {
+		close(out)
+	}
+	wg.Wait()
+	return time.Since(start)
+}
+
+// fan in/out test sweeps the number of select cases, select locks every
+// channel involved and polls them in random order, so it gets slower per
+// message as cases are added
+func fanInOutTest(maxChannels int, messages int) float64 {
 	totalTime := 0.0
+	fmt.Fprintf(os.Stderr, "%-10s %14s %14s\n", "channels", "fan-in ns/msg", "fan-out ns/msg")
+	for n := 1; n <= maxChannels; n *= 2 {
+		if selectRecvN[n] == nil {
+			fmt.Fprintf(os.Stderr, "fan in/out: no generated select with %d cases, stopping at %d\n", n, n/2)
+			break
+		}
+		in := fanIn(n, messages)
+		out := fanOut(n, messages)
+		totalTime += float64((in + out).Nanoseconds()) / 1000000.0
+		sent := float64(messages / n * n)
+		fmt.Fprintf(os.Stderr, "%-10d %14.1f %14.1f\n", n, float64(in.Nanoseconds())/sent, float64(out.Nanoseconds())/sent)
+	}
+	return totalTime
+}
 
-	totalTime += parallelHttpTest(50 * scaleFactor)
-	totalTime += producerConsumerTest(4, 1000*scaleFactor)
-	totalTime += parallelMathTest(4, 100*scaleFactor)
-	totalTime += asyncFileTest(20 * scaleFactor)
-	totalTime += threadPoolTest(8, 500*scaleFactor)
+// deepStack recurses with a frame big enough that a few levels push the
+// goroutine past its starting 2KB stack and make the runtime grow it
+//
+//go:noinline
+func deepStack(depth int) int64 {
+	var frame [128]int64
+	frame[depth%len(frame)] = int64(depth)
+	if depth == 0 {
+		return frame[0]
+	}
+	return deepStack(depth-1) + frame[depth%len(frame)]
+}
 
-	fmt.Printf("%.3f\n", totalTime)
-}
\ No newline at end of file
+// goroutine spawn test starts and joins short-lived goroutines one at a
+// time, which is the spawn latency, and in bursts that are all alive at once,
+// which shows the peak goroutine count and the stack memory they hold
+func goroutineSpawnTest(total int, burst int) float64 {
+	start := time.Now()
+	done := make(chan struct{})
+	for i := 0; i < total; i++ {
+		go func() {
+			done <- struct{}{}
+		}()
+		<-done
+	}
+	sequential := time.Since(start)
+	totalTime := float64(sequential.Nanoseconds()) / 1000000.0
+	fmt.Fprintf(os.Stderr, "goroutine spawn sequential: %d goroutines, %.0f ns spawn+join\n",
+		total, float64(sequential.Nanoseconds())/float64(total))
+
+	for _, depth := range []int{0, 8} {
+		// dead goroutines' stacks are only freed by a collection
+		var stackBefore, stackPeak runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&stackBefore)
+		peakGoroutines := 0
+
+		start := time.Now()
+		var checksum int64
+		for spawned := 0; spawned < total; spawned += burst {
+			release := make(chan struct{})
+			var started, finished sync.WaitGroup
+			for i := 0; i < burst; i++ {
+				started.Add(1)
+				finished.Add(1)
+				go func() {
+					defer finished.Done()
+					v := deepStack(depth)
+					started.Done()
+					<-release
+					atomic.AddInt64(&checksum, v)
+				}()
+			}
+			// everything in the burst is parked on release here
+			started.Wait()
+			if spawned == 0 {
+				peakGoroutines = runtime.NumGoroutine()
+				runtime.ReadMemStats(&stackPeak)
+			}
+			close(release)
+			finished.Wait()
+		}
+		duration := time.Since(start)
+		_ = atomic.LoadInt64(&checksum) // prevent optimization
+		totalTime += float64(duration.Nanoseconds()) / 1000000.0
+
+		fmt.Fprintf(os.Stderr, "goroutine spawn burst %d (stack depth %d): %.0f ns/goroutine, peak %d goroutines, stack %.1f MB -> %.1f MB (%.1f KB each)\n",
+			burst, depth, float64(duration.Nanoseconds())/float64(total), peakGoroutines,
+			float64(stackBefore.StackInuse)/(1024*1024), float64(stackPeak.StackInuse)/(1024*1024),
+			float64(stackPeak.StackInuse-stackBefore.StackInuse)/1024/float64(burst))
+	}
+	return totalTime
+}
+
+// taskGroup is the errgroup.Group surface the errgroup test needs
+type taskGroup interface {
+	Go(f func() error)
+	Wait() error
+}
+
+// groupImpl makes a group with at most limit tasks running, whose context is
+// cancelled by the first error
+type groupImpl struct {
+	name string
+	new  func(ctx context.Context, limit int) (taskGroup, context.Context)
+}
+
+// groupImpls holds the built-in group, the errgroup tag adds x/sync's
+var groupImpls = []groupImpl{
+	{"stdlib group", func(ctx context.Context, limit int) (taskGroup, context.Context) {
+		return newLimitGroup(ctx, limit)
+	}},
+}
+
+// limitGroup is errgroup.WithContext plus SetLimit on the standard library,
+// Go blocks while limit tasks are running
+type limitGroup struct {
+	wg     sync.WaitGroup
+	tokens chan struct{}
+	cancel context.CancelFunc
+	once   sync.Once
+	err    error
+}
+
+func newLimitGroup(ctx context.Context, limit int) (*limitGroup, context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	return &limitGroup{tokens: make(chan struct{}, limit), cancel: cancel}, ctx
+}
+
+func (g *limitGroup) Go(f func() error) {
+	g.tokens <- struct{}{}
+	g.wg.Add(1)
+	go func() {
+		defer func() {
+			<-g.tokens
+			g.wg.Done()
+		}()
+		if err := f(); err != nil {
+			g.once.Do(func() {
+				g.err = err
+				g.cancel()
+			})
+		}
+	}()
+}
+
+func (g *limitGroup) Wait() error {
+	g.wg.Wait()
+	g.cancel()
+	return g.err
+}
+
+// mathWorkUnit is one unit of the parallel math workload
+func mathWorkUnit() int64 {
+	sum := fibonacci(35)
+	for k := 0; k < 1000; k++ {
+		sum += int64(k * k)
+	}
+	return sum
+}
+
+// errgroup test runs the parallel math work as one task per unit under each
+// group with a limit of numThreads, against plain WaitGroup workers, then
+// fails one task early to show how many of the rest get skipped
+func errgroupTest(numThreads int, workPerThread int) float64 {
+	tasks := numThreads * workPerThread
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	var totalSum int64
+	for i := 0; i < numThreads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var localSum int64
+			for j := 0; j < workPerThread; j++ {
+				localSum += mathWorkUnit()
+			}
+			atomic.AddInt64(&totalSum, localSum)
+		}()
+	}
+	wg.Wait()
+	baseline := time.Since(start)
+	totalTime := float64(baseline.Nanoseconds()) / 1000000.0
+	fmt.Fprintf(os.Stderr, "errgroup: waitgroup %d workers, %.1f ns/task\n", numThreads, float64(baseline.Nanoseconds())/float64(tasks))
+
+	errInjected := errors.New("injected failure")
+	for _, impl := range groupImpls {
+		start := time.Now()
+		g, _ := impl.new(context.Background(), numThreads)
+		var sum int64
+		for i := 0; i < tasks; i++ {
+			g.Go(func() error {
+				atomic.AddInt64(&sum, mathWorkUnit())
+				return nil
+			})
+		}
+		if err := g.Wait(); err != nil {
+			fmt.Fprintf(os.Stderr, "errgroup: %s failed -> %v\n", impl.name, err)
+		}
+		full := time.Since(start)
+
+		// the tenth of the way in task fails, everything still queued after it
+		// should see the cancelled context and bail out
+		start = time.Now()
+		g, ctx := impl.new(context.Background(), numThreads)
+		var ran int64
+		for i := 0; i < tasks; i++ {
+			g.Go(func() error {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				atomic.AddInt64(&ran, 1)
+				if i == tasks/10 {
+					return errInjected
+				}
+				atomic.AddInt64(&sum, mathWorkUnit())
+				return nil
+			})
+		}
+		err := g.Wait()
+		cancelled := time.Since(start)
+		totalTime += float64((full + cancelled).Nanoseconds()) / 1000000.0
+
+		fmt.Fprintf(os.Stderr, "errgroup: %s limit %d, %.1f ns/task (%+.1f%% vs waitgroup), early error ran %d of %d tasks in %.3f ms (%v)\n",
+			impl.name, numThreads, float64(full.Nanoseconds())/float64(tasks),
+			(float64(full)/float64(baseline)-1)*100, atomic.LoadInt64(&ran), tasks,
+			float64(cancelled.Nanoseconds())/1000000.0, err)
+	}
+	return totalTime
+}
+
+// join test spawns many trivial goroutines and waits for them with each
+// completion mechanism, the work is one atomic add so what's left is the
+// cost of spawning and signalling completion
+func joinTest(tasks int) float64 {
+	var sink int64
+	work := func(i int) { atomic.AddInt64(&sink, int64(i)) }
+
+	type join struct {
+		name string
+		run  func()
+	}
+	joins := []join{
+		{"waitgroup", func() {
+			var wg sync.WaitGroup
+			for i := 0; i < tasks; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					work(i)
+				}()
+			}
+			wg.Wait()
+		}},
+		{"done channels", func() {
+			done := make([]chan struct{}, tasks)
+			for i := range done {
+				done[i] = make(chan struct{})
+				go func() {
+					work(i)
+					close(done[i])
+				}()
+			}
+			for _, ch := range done {
+				<-ch
+			}
+		}},
+	}
+	for _, impl := range groupImpls {
+		joins = append(joins, join{impl.name, func() {
+			// no real limit, only the joining is compared
+			g, _ := impl.new(context.Background(), tasks)
+			for i := 0; i < tasks; i++ {
+				g.Go(func() error {
+					work(i)
+					return nil
+				})
+			}
+			g.Wait()
+		}})
+	}
+
+	totalTime := 0.0
+	for _, join := range joins {
+		start := time.Now()
+		join.run()
+		duration := time.Since(start)
+		totalTime += float64(duration.Nanoseconds()) / 1000000.0
+		fmt.Fprintf(os.Stderr, "join %-16s %d tasks, %.1f ns/task\n", join.name, tasks, float64(duration.Nanoseconds())/float64(tasks))
+	}
+	_ = atomic.LoadInt64(&sink) // prevent optimization
+	return totalTime
+}
+
+// timer test has several goroutines create and cancel timers that would
+// fire a minute from now, the way request deadlines mostly get cancelled,
+// then collects and checks what heap the timers left behind
+func timerTest(numGoroutines int, timersPerGoroutine int) float64 {
+	const far = time.Minute
+	variants := []struct {
+		name string
+		op   func()
+	}{
+		{"time.NewTimer+Stop", func() {
+			t := time.NewTimer(far)
+			t.Stop()
+		}},
+		{"time.AfterFunc+Stop", func() {
+			t := time.AfterFunc(far, func() {})
+			t.Stop()
+		}},
+		{"time.After abandoned", func() {
+			// nothing can stop it, it's only reclaimed once unreachable
+			select {
+			case <-time.After(far):
+			default:
+			}
+		}},
+		{"context.WithTimeout", func() {
+			_, cancel := context.WithTimeout(context.Background(), far)
+			cancel()
+		}},
+	}
+
+	totalTime := 0.0
+	for _, v := range variants {
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+
+		start := time.Now()
+		var wg sync.WaitGroup
+		for g := 0; g < numGoroutines; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < timersPerGoroutine; i++ {
+					v.op()
+				}
+			}()
+		}
+		wg.Wait()
+		duration := time.Since(start)
+		totalTime += float64(duration.Nanoseconds()) / 1000000.0
+
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		total := numGoroutines * timersPerGoroutine
+		fmt.Fprintf(os.Stderr, "timers %-22s %d timers, %.1f ns/timer, %.1f MB allocated, %+.1f KB live after gc\n",
+			v.name, total, float64(duration.Nanoseconds())/float64(total),
+			float64(after.TotalAlloc-before.TotalAlloc)/(1024*1024), (float64(after.HeapAlloc)-float64(before.HeapAlloc))/1024)
+	}
+	return totalTime
+}
+
+// scheduler latency test has a probe goroutine sleep for a fixed interval
+// over and over while a growing number of goroutines spin on the CPU, and
+// records how late each wakeup was. the overshoot is timer slack plus the
+// wait for a P once the probe is runnable again, the tail is what matters.
+// when spinners hold every P the probe can wait for async preemption, tens
+// of ms, so each level stops early once it has used up budget
+func schedulerLatencyTest(loadLevels []int, samples int, interval time.Duration, budget time.Duration) float64 {
+	totalTime := 0.0
+	for _, load := range loadLevels {
+		var stop atomic.Bool
+		var spinners sync.WaitGroup
+		for g := 0; g < load; g++ {
+			spinners.Add(1)
+			go func() {
+				defer spinners.Done()
+				var x uint64
+				for !stop.Load() {
+					for i := 0; i < 1000; i++ {
+						x = x*6364136223846793005 + 1442695040888963407
+					}
+				}
+				_ = x // prevent optimization
+			}()
+		}
+
+		overshoots := make([]time.Duration, 0, samples)
+		start := time.Now()
+		for len(overshoots) < samples && time.Since(start) < budget {
+			sleepStart := time.Now()
+			time.Sleep(interval)
+			overshoots = append(overshoots, time.Since(sleepStart)-interval)
+		}
+		duration := time.Since(start)
+		stop.Store(true)
+		spinners.Wait()
+		totalTime += float64(duration.Nanoseconds()) / 1000000.0
+
+		sort.Slice(overshoots, func(i, j int) bool { return overshoots[i] < overshoots[j] })
+		percentile := func(p float64) float64 {
+			return float64(overshoots[int(p*float64(len(overshoots)-1))].Microseconds()) / 1000.0
+		}
+		fmt.Fprintf(os.Stderr, "scheduler latency, %3d spinning goroutines, GOMAXPROCS %d: %d x %v sleep, overshoot p50 %.3f ms, p99 %.3f ms, p99.9 %.3f ms, max %.3f ms\n",
+			load, runtime.GOMAXPROCS(0), len(overshoots), interval, percentile(0.50), percentile(0.99), percentile(0.999), percentile(1))
+	}
+	return totalTime
+}
+
+// timeout test runs a request loop where every call waits on a reply channel
+// with a per-call timeout, built with select and time.After, with one timer
+// per goroutine that's Reset before each call, and with context.WithTimeout.
+// replies always come back well within the timeout, so the difference is
+// purely what setting up and tearing down the timeout costs per call
+func timeoutTest(numGoroutines int, callsPerGoroutine int, timeout time.Duration) float64 {
+	mechanisms := []struct {
+		name string
+		call func(timer *time.Timer, reply <-chan int) bool
+	}{
+		{"select+time.After", func(_ *time.Timer, reply <-chan int) bool {
+			select {
+			case <-reply:
+				return true
+			case <-time.After(timeout):
+				return false
+			}
+		}},
+		{"reused timer", func(timer *time.Timer, reply <-chan int) bool {
+			// since go 1.23 Reset drops a stale fire, no drain needed
+			timer.Reset(timeout)
+			select {
+			case <-reply:
+				timer.Stop()
+				return true
+			case <-timer.C:
+				return false
+			}
+		}},
+		{"context.WithTimeout", func(_ *time.Timer, reply <-chan int) bool {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			select {
+			case <-reply:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}},
+	}
+
+	totalTime := 0.0
+	for _, m := range mechanisms {
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+
+		var timedOut atomic.Int64
+		start := time.Now()
+		var wg sync.WaitGroup
+		for g := 0; g < numGoroutines; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				requests := make(chan int)
+				reply := make(chan int, 1)
+				go func() {
+					for req := range requests {
+						reply <- req
+					}
+				}()
+				defer close(requests)
+
+				timer := time.NewTimer(timeout)
+				timer.Stop()
+				for i := 0; i < callsPerGoroutine; i++ {
+					requests <- i
+					if !m.call(timer, reply) {
+						timedOut.Add(1)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		duration := time.Since(start)
+		totalTime += float64(duration.Nanoseconds()) / 1000000.0
+
+		runtime.ReadMemStats(&after)
+		calls := float64(numGoroutines * callsPerGoroutine)
+		fmt.Fprintf(os.Stderr, "timeouts %-20s %.0f calls, %.1f ns/call, %.2f allocs/call, %.1f B/call, %d timed out\n",
+			m.name, calls, float64(duration.Nanoseconds())/calls, float64(after.Mallocs-before.Mallocs)/calls,
+			float64(after.TotalAlloc-before.TotalAlloc)/calls, timedOut.Load())
+	}
+	return totalTime
+}
+
+// promise is a write-once result, resolve is called exactly once and get
+// blocks until it has been
+type promise interface {
+	resolve(value int64)
+	get() int64
+}
+
+// chanPromise hands the value over through a channel of one, the first get
+// takes it out and later gets reuse the cached copy
+type chanPromise struct {
+	ch    chan int64
+	once  sync.Once
+	value int64
+}
+
+func newChanPromise() promise { return &chanPromise{ch: make(chan int64, 1)} }
+
+func (p *chanPromise) resolve(value int64) { p.ch <- value }
+
+func (p *chanPromise) get() int64 {
+	p.once.Do(func() { p.value = <-p.ch })
+	return p.value
+}
+
+// condPromise parks getters on a cond until resolve flips done
+type condPromise struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	done  bool
+	value int64
+}
+
+func newCondPromise() promise {
+	p := &condPromise{}
+	p.cond = sync.NewCond(&p.mu)
+	return p
+}
+
+func (p *condPromise) resolve(value int64) {
+	p.mu.Lock()
+	p.value = value
+	p.done = true
+	p.mu.Unlock()
+	p.cond.Broadcast()
+}
+
+func (p *condPromise) get() int64 {
+	p.mu.Lock()
+	for !p.done {
+		p.cond.Wait()
+	}
+	value := p.value
+	p.mu.Unlock()
+	return value
+}
+
+// async runs fn on its own goroutine and returns a promise for the result
+func async(newPromise func() promise, fn func() int64) promise {
+	p := newPromise()
+	go func() { p.resolve(fn()) }()
+	return p
+}
+
+// then chains fn onto p, the step only starts once p is resolved
+func then(newPromise func() promise, p promise, fn func(int64) int64) promise {
+	return async(newPromise, func() int64 { return fn(p.get()) })
+}
+
+// all fans several promises into one that resolves to their sum
+func all(newPromise func() promise, ps []promise) promise {
+	return async(newPromise, func() int64 {
+		var sum int64
+		for _, p := range ps {
+			sum += p.get()
+		}
+		return sum
+	})
+}
+
+// futureStep is kept tiny so the test measures the composition, not the work
+func futureStep(x int64) int64 { return x*6364136223846793005 + 1442695040888963407 }
+
+// future test builds chains of then steps and fan-ins of async calls with
+// each promise kind and compares them with calling futureStep directly,
+// the per-step difference is what promise-style composition costs
+func futureTest(chains int, chainLength int, fanWidth int) float64 {
+	kinds := []struct {
+		name       string
+		newPromise func() promise
+	}{
+		{"channel of one", newChanPromise},
+		{"mutex+cond", newCondPromise},
+	}
+
+	var sink int64
+	start := time.Now()
+	for c := 0; c < chains; c++ {
+		x := int64(c)
+		for i := 0; i < chainLength; i++ {
+			x = futureStep(x)
+		}
+		sink += x
+		for i := 0; i < fanWidth; i++ {
+			sink += futureStep(int64(i))
+		}
+	}
+	direct := time.Since(start)
+	steps := chains * (chainLength + fanWidth)
+	fmt.Fprintf(os.Stderr, "futures %-16s %d chains of %d, fan-in %d, %.1f ns/step\n",
+		"direct calls", chains, chainLength, fanWidth, float64(direct.Nanoseconds())/float64(steps))
+
+	totalTime := float64(direct.Nanoseconds()) / 1000000.0
+	for _, kind := range kinds {
+		var checksum int64
+		var chainTime time.Duration
+		start := time.Now()
+		for c := 0; c < chains; c++ {
+			chainStart := time.Now()
+			p := async(kind.newPromise, func() int64 { return int64(c) })
+			for i := 0; i < chainLength; i++ {
+				p = then(kind.newPromise, p, futureStep)
+			}
+			checksum += p.get()
+			chainTime += time.Since(chainStart)
+
+			fan := make([]promise, fanWidth)
+			for i := range fan {
+				fan[i] = async(kind.newPromise, func() int64 { return futureStep(int64(i)) })
+			}
+			checksum += all(kind.newPromise, fan).get()
+		}
+		duration := time.Since(start)
+		totalTime += float64(duration.Nanoseconds()) / 1000000.0
+
+		if checksum != sink {
+			fmt.Fprintf(os.Stderr, "futures %s checksum %d, want %d\n", kind.name, checksum, sink)
+		}
+		chainSteps := chains * chainLength
+		fanSteps := chains * fanWidth
+		fmt.Fprintf(os.Stderr, "futures %-16s chain %.1f ns/step, fan-in %.1f ns/step, overhead %.1f ns/step over direct\n",
+			kind.name, float64(chainTime.Nanoseconds())/float64(chainSteps),
+			float64((duration-chainTime).Nanoseconds())/float64(fanSteps),
+			float64((duration-direct).Nanoseconds())/float64(steps))
+	}
+
+	return totalTime
+}
+
+// actorMsg is passed from actor to actor until hops runs out
+type actorMsg struct {
+	sent time.Time
+	hops int
+}
+
+// actor test starts one goroutine per actor, each reading its own bounded
+// mailbox and forwarding every message either to the next actor in a ring
+// or to a random one. in-flight messages never exceed the mailbox size, so
+// no mailbox can fill up and two actors can't deadlock sending to each other
+func actorTest(numActors int, mailboxSize int, hopsPerMessage int) float64 {
+	topologies := []struct {
+		name string
+		next func(self int, rng *rand.Rand) int
+	}{
+		{"ring", func(self int, _ *rand.Rand) int { return (self + 1) % numActors }},
+		{"random", func(_ int, rng *rand.Rand) int { return rng.Intn(numActors) }},
+	}
+	inFlight := mailboxSize
+
+	totalTime := 0.0
+	for _, topology := range topologies {
+		mailboxes := make([]chan actorMsg, numActors)
+		for i := range mailboxes {
+			mailboxes[i] = make(chan actorMsg, mailboxSize)
+		}
+		latencies := make([][]time.Duration, numActors)
+
+		var finished, actors sync.WaitGroup
+		finished.Add(inFlight)
+		actors.Add(numActors)
+		for a := 0; a < numActors; a++ {
+			go func() {
+				defer actors.Done()
+				rng := rand.New(rand.NewSource(int64(a)))
+				for msg := range mailboxes[a] {
+					now := time.Now()
+					latencies[a] = append(latencies[a], now.Sub(msg.sent))
+					if msg.hops == 0 {
+						finished.Done()
+						continue
+					}
+					mailboxes[topology.next(a, rng)] <- actorMsg{sent: now, hops: msg.hops - 1}
+				}
+			}()
+		}
+
+		start := time.Now()
+		for m := 0; m < inFlight; m++ {
+			mailboxes[m*numActors/inFlight] <- actorMsg{sent: time.Now(), hops: hopsPerMessage - 1}
+		}
+		finished.Wait()
+		duration := time.Since(start)
+		for _, mailbox := range mailboxes {
+			close(mailbox)
+		}
+		actors.Wait()
+		totalTime += float64(duration.Nanoseconds()) / 1000000.0
+
+		var all []time.Duration
+		for _, l := range latencies {
+			all = append(all, l...)
+		}
+		sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+		percentile := func(p float64) float64 {
+			return float64(all[int(p*float64(len(all)-1))].Nanoseconds()) / 1000.0
+		}
+		fmt.Fprintf(os.Stderr, "actors %-6s %d actors, mailbox %d, %d messages, %.0f msg/s, latency p50 %.1f us, p99 %.1f us, p99.9 %.1f us, max %.1f us\n",
+			topology.name, numActors, mailboxSize, len(all), float64(len(all))/duration.Seconds(),
+			percentile(0.50), percentile(0.99), percentile(0.999), percentile(1))
+	}
+	return totalTime
+}
+
+// lazyValue is what every lazy init variant builds on first use
+type lazyValue []int64
+
+type lazyInit struct {
+	name string
+	new  func(build func() *lazyValue) func() *lazyValue
+}
+
+var lazyInits = []lazyInit{
+	{"sync.Once", func(build func() *lazyValue) func() *lazyValue {
+		var once sync.Once
+		var value *lazyValue
+		return func() *lazyValue {
+			once.Do(func() { value = build() })
+			return value
+		}
+	}},
+	{"sync.OnceValue", func(build func() *lazyValue) func() *lazyValue {
+		return sync.OnceValue(build)
+	}},
+	{"double-checked atomic", func(build func() *lazyValue) func() *lazyValue {
+		var mu sync.Mutex
+		var value atomic.Pointer[lazyValue]
+		return func() *lazyValue {
+			if v := value.Load(); v != nil {
+				return v
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if value.Load() == nil {
+				value.Store(build())
+			}
+			return value.Load()
+		}
+	}},
+	{"mutex every call", func(build func() *lazyValue) func() *lazyValue {
+		var mu sync.Mutex
+		var value *lazyValue
+		return func() *lazyValue {
+			mu.Lock()
+			defer mu.Unlock()
+			if value == nil {
+				value = build()
+			}
+			return value
+		}
+	}},
+}
+
+// once test releases every goroutine at the same instant on a fresh lazy
+// value, so they all race the first call, then keeps them reading it. the
+// first call is timed apart from the steady state reads, which is where the
+// variants really differ once the value exists
+func onceTest(numGoroutines int, readsPerGoroutine int) float64 {
+	totalTime := 0.0
+	for _, impl := range lazyInits {
+		var builds atomic.Int32
+		get := impl.new(func() *lazyValue {
+			builds.Add(1)
+			v := make(lazyValue, 1024)
+			for i := range v {
+				v[i] = int64(i)
+			}
+			return &v
+		})
+
+		var ready, initialized, done sync.WaitGroup
+		release := make(chan struct{})
+		ready.Add(numGoroutines)
+		initialized.Add(numGoroutines)
+		done.Add(numGoroutines)
+		var sink atomic.Int64
+		for g := 0; g < numGoroutines; g++ {
+			go func() {
+				defer done.Done()
+				ready.Done()
+				<-release
+				sum := (*get())[g%1024]
+				initialized.Done()
+				initialized.Wait()
+				for i := 0; i < readsPerGoroutine; i++ {
+					sum += (*get())[i&1023]
+				}
+				sink.Add(sum)
+			}()
+		}
+
+		ready.Wait()
+		start := time.Now()
+		close(release)
+		initialized.Wait()
+		firstCall := time.Since(start)
+		done.Wait()
+		duration := time.Since(start)
+		totalTime += float64(duration.Nanoseconds()) / 1000000.0
+
+		reads := numGoroutines * readsPerGoroutine
+		fmt.Fprintf(os.Stderr, "lazy init %-22s %d goroutines, first call %.3f ms, %d builds, steady read %.2f ns/op\n",
+			impl.name, numGoroutines, float64(firstCall.Nanoseconds())/1000000.0, builds.Load(),
+			float64((duration-firstCall).Nanoseconds())/float64(reads))
+		_ = sink.Load() // prevent optimization
+	}
+	return totalTime
+}
+
+// limiter caps how many holders are inside at once
+type limiter interface {
+	acquire()
+	release()
+}
+
+type limiterImpl struct {
+	name string
+	new  func(limit int) limiter
+}
+
+// limiterImpls are the built-in limiters, the semaphore tag adds x/sync's
+// semaphore.Weighted
+var limiterImpls = []limiterImpl{
+	{"channel tokens", func(limit int) limiter { return make(tokenLimiter, limit) }},
+	{"counter+cond", func(limit int) limiter { return newCondLimiter(limit) }},
+}
+
+// tokenLimiter holds a token in the buffer for every holder
+type tokenLimiter chan struct{}
+
+func (l tokenLimiter) acquire() { l <- struct{}{} }
+func (l tokenLimiter) release() { <-l }
+
+// condLimiter counts holders under a mutex and parks waiters on a cond
+type condLimiter struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	active int
+	limit  int
+}
+
+func newCondLimiter(limit int) *condLimiter {
+	l := &condLimiter{limit: limit}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+func (l *condLimiter) acquire() {
+	l.mu.Lock()
+	for l.active >= l.limit {
+		l.cond.Wait()
+	}
+	l.active++
+	l.mu.Unlock()
+}
+
+func (l *condLimiter) release() {
+	l.mu.Lock()
+	l.active--
+	l.mu.Unlock()
+	l.cond.Signal()
+}
+
+// semaphore test has many more goroutines than the limit acquire, do a
+// sliver of work and release over and over, so it's all limiter churn
+func semaphoreTest(numGoroutines int, limit int, opsPerGoroutine int) float64 {
+	totalTime := 0.0
+	for _, impl := range limiterImpls {
+		l := impl.new(limit)
+		start := time.Now()
+
+		var wg sync.WaitGroup
+		var inside, peak int64
+		for g := 0; g < numGoroutines; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < opsPerGoroutine; i++ {
+					l.acquire()
+					n := atomic.AddInt64(&inside, 1)
+					if n > atomic.LoadInt64(&peak) {
+						atomic.StoreInt64(&peak, n)
+					}
+					atomic.AddInt64(&inside, -1)
+					l.release()
+				}
+			}()
+		}
+		wg.Wait()
+
+		duration := time.Since(start)
+		totalTime += float64(duration.Nanoseconds()) / 1000000.0
+		ops := numGoroutines * opsPerGoroutine
+		fmt.Fprintf(os.Stderr, "semaphore %s: %d goroutines, limit %d, %.1f ns/acquire+release, peak %d inside\n",
+			impl.name, numGoroutines, limit, float64(duration.Nanoseconds())/float64(ops), atomic.LoadInt64(&peak))
+	}
+	return totalTime
+}
+
+// rateLimiter blocks until the caller may go ahead or ctx is done
+type rateLimiter interface {
+	wait(ctx context.Context) error
+}
+
+type rateLimiterImpl struct {
+	name string
+	new  func(perSecond float64, burst int) rateLimiter
+}
+
+// rateLimiterImpls are the built-in rate limiters, the rate tag adds x/time's
+// token bucket
+var rateLimiterImpls = []rateLimiterImpl{
+	{"sliding window", func(perSecond float64, burst int) rateLimiter { return newSlidingWindow(perSecond, burst) }},
+}
+
+// slidingWindow admits at most burst events in any window of burst/perSecond,
+// keeping the admit times of the last burst events in a ring. a caller that
+// has to wait reserves the next free slot before sleeping, so waiters are
+// served in order, a cancelled wait still uses up its slot
+type slidingWindow struct {
+	mu     sync.Mutex
+	window time.Duration
+	times  []time.Time
+	head   int
+}
+
+func newSlidingWindow(perSecond float64, burst int) *slidingWindow {
+	burst = max(burst, 1)
+	return &slidingWindow{
+		window: time.Duration(float64(burst) / perSecond * float64(time.Second)),
+		times:  make([]time.Time, burst),
+	}
+}
+
+func (w *slidingWindow) wait(ctx context.Context) error {
+	w.mu.Lock()
+	now := time.Now()
+	at := w.times[w.head].Add(w.window)
+	if at.Before(now) {
+		at = now
+	}
+	w.times[w.head] = at
+	w.head = (w.head + 1) % len(w.times)
+	w.mu.Unlock()
+
+	if delay := at.Sub(now); delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// rate limit test feeds tasks into a WorkerPool from several producers, each
+// waiting on a shared limiter before every Submit, and checks the rate that
+// comes out against the configured one for a few limits and bursts. a last
+// pass with an effectively unlimited rate shows what a wait costs when it
+// never has to sleep
+func rateLimitTest(producers int, limits []float64, bursts []int, runFor time.Duration) float64 {
+	ctx := context.Background()
+	totalTime := 0.0
+	for _, impl := range rateLimiterImpls {
+		for _, limit := range limits {
+			for _, burst := range bursts {
+				limiter := impl.new(limit, burst)
+				tasks := burst + int(limit*runFor.Seconds())
+				per := (tasks + producers - 1) / producers
+
+				pool := NewWorkerPool(producers)
+				var executed atomic.Int64
+				waits := make([][]time.Duration, producers)
+				start := time.Now()
+				var wg sync.WaitGroup
+				for p := 0; p < producers; p++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						for i := p * per; i < min((p+1)*per, tasks); i++ {
+							waitStart := time.Now()
+							if err := limiter.wait(ctx); err != nil {
+								return
+							}
+							waits[p] = append(waits[p], time.Since(waitStart))
+							pool.Submit(ctx, func() { executed.Add(1) })
+						}
+					}()
+				}
+				wg.Wait()
+				pool.Wait()
+				pool.Close()
+				duration := time.Since(start)
+				totalTime += float64(duration.Nanoseconds()) / 1000000.0
+
+				var all []time.Duration
+				for _, w := range waits {
+					all = append(all, w...)
+				}
+				sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+				percentile := func(p float64) float64 {
+					return float64(all[int(p*float64(len(all)-1))].Microseconds()) / 1000.0
+				}
+				// the first burst tasks go straight through, the rest are paced
+				achieved := float64(executed.Load()-int64(burst)) / duration.Seconds()
+				fmt.Fprintf(os.Stderr, "rate limit %-20s limit %7.0f/s burst %4d: %7.0f/s achieved (%.1f%%), wait p50 %.3f ms, p99 %.3f ms\n",
+					impl.name, limit, burst, achieved, 100*achieved/limit, percentile(0.50), percentile(0.99))
+			}
+		}
+
+		limiter := impl.new(1e12, 1<<16)
+		calls := 100000
+		start := time.Now()
+		var wg sync.WaitGroup
+		for p := 0; p < producers; p++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < calls; i++ {
+					limiter.wait(ctx)
+				}
+			}()
+		}
+		wg.Wait()
+		duration := time.Since(start)
+		totalTime += float64(duration.Nanoseconds()) / 1000000.0
+		fmt.Fprintf(os.Stderr, "rate limit %-20s unlimited, %d producers: %.1f ns/wait\n",
+			impl.name, producers, float64(duration.Nanoseconds())/float64(producers*calls))
+	}
+	return totalTime
+}
+
+// concurrentMap is the surface the map test drives
+type concurrentMap interface {
+	load(key int64) (int64, bool)
+	store(key, value int64)
+}
+
+type syncMap struct{ m sync.Map }
+
+func (m *syncMap) load(key int64) (int64, bool) {
+	v, ok := m.m.Load(key)
+	if !ok {
+		return 0, false
+	}
+	return v.(int64), true
+}
+
+func (m *syncMap) store(key, value int64) { m.m.Store(key, value) }
+
+type mutexMap struct {
+	mu sync.Mutex
+	m  map[int64]int64
+}
+
+func (m *mutexMap) load(key int64) (int64, bool) {
+	m.mu.Lock()
+	v, ok := m.m[key]
+	m.mu.Unlock()
+	return v, ok
+}
+
+func (m *mutexMap) store(key, value int64) {
+	m.mu.Lock()
+	m.m[key] = value
+	m.mu.Unlock()
+}
+
+type rwMutexMap struct {
+	mu sync.RWMutex
+	m  map[int64]int64
+}
+
+func (m *rwMutexMap) load(key int64) (int64, bool) {
+	m.mu.RLock()
+	v, ok := m.m[key]
+	m.mu.RUnlock()
+	return v, ok
+}
+
+func (m *rwMutexMap) store(key, value int64) {
+	m.mu.Lock()
+	m.m[key] = value
+	m.mu.Unlock()
+}
+
+// shardedMap spreads keys over rwmutex maps so writers to different shards
+// don't block each other
+type shardedMap struct {
+	shards []rwMutexMap
+}
+
+func newShardedMap(shards int) *shardedMap {
+	m := &shardedMap{shards: make([]rwMutexMap, shards)}
+	for i := range m.shards {
+		m.shards[i].m = make(map[int64]int64)
+	}
+	return m
+}
+
+func (m *shardedMap) shard(key int64) *rwMutexMap {
+	// fibonacci hashing so sequential keys spread out
+	return &m.shards[(uint64(key)*11400714819323198485)>>32%uint64(len(m.shards))]
+}
+
+func (m *shardedMap) load(key int64) (int64, bool) { return m.shard(key).load(key) }
+func (m *shardedMap) store(key, value int64)       { m.shard(key).store(key, value) }
+
+// concurrent map test runs random reads and writes over a prefilled key space
+// at each read share and goroutine count, against each map
+func concurrentMapTest(maxGoroutines int, opsPerGoroutine int, keySpace int) float64 {
+	maps := []struct {
+		name string
+		new  func() concurrentMap
+	}{
+		{"sync.Map", func() concurrentMap { return &syncMap{} }},
+		{"mutex map", func() concurrentMap { return &mutexMap{m: make(map[int64]int64)} }},
+		{"rwmutex map", func() concurrentMap { return &rwMutexMap{m: make(map[int64]int64)} }},
+		{"sharded map 32", func() concurrentMap { return newShardedMap(32) }},
+	}
+	var counts []int
+	for n := 1; n <= maxGoroutines; n *= 2 {
+		counts = append(counts, n)
+	}
+
+	totalTime := 0.0
+	for _, readPercent := range []int{90, 50} {
+		fmt.Fprintf(os.Stderr, "%-24s", fmt.Sprintf("M ops/s, %d%% reads", readPercent))
+		for _, n := range counts {
+			fmt.Fprintf(os.Stderr, " %8s", fmt.Sprintf("%dg", n))
+		}
+		fmt.Fprintln(os.Stderr)
+
+		for _, impl := range maps {
+			fmt.Fprintf(os.Stderr, "%-24s", impl.name)
+			for _, n := range counts {
+				m := impl.new()
+				for k := 0; k < keySpace; k++ {
+					m.store(int64(k), int64(k))
+				}
+
+				start := time.Now()
+				var wg sync.WaitGroup
+				var checksum int64
+				for g := 0; g < n; g++ {
+					wg.Add(1)
+					go func(seed uint64) {
+						defer wg.Done()
+						var sum int64
+						x := seed*2654435761 + 1
+						for i := 0; i < opsPerGoroutine; i++ {
+							x ^= x << 13
+							x ^= x >> 7
+							x ^= x << 17
+							key := int64(x % uint64(keySpace))
+							if int(x>>40%100) < readPercent {
+								v, _ := m.load(key)
+								sum += v
+							} else {
+								m.store(key, int64(i))
+							}
+						}
+						atomic.AddInt64(&checksum, sum)
+					}(uint64(g))
+				}
+				wg.Wait()
+
+				duration := time.Since(start)
+				_ = atomic.LoadInt64(&checksum) // prevent optimization
+				totalTime += float64(duration.Nanoseconds()) / 1000000.0
+				fmt.Fprintf(os.Stderr, " %8.2f", float64(n*opsPerGoroutine)/duration.Seconds()/1e6)
+			}
+			fmt.Fprintln(os.Stderr)
+		}
+	}
+	return totalTime
+}
+
+// boundedCache holds at most its capacity of entries, evicting to make room
+type boundedCache interface {
+	get(key int64) (int64, bool)
+	put(key, value int64)
+}
+
+type lruEntry struct {
+	key, value int64
+}
+
+// lruCache is a map plus a recency list under one mutex, every get moves the
+// entry to the front so even hits take the lock exclusively
+type lruCache struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[int64]*list.Element
+	order    *list.List
+}
+
+func newLRUCache(capacity int) *lruCache {
+	return &lruCache{capacity: max(capacity, 1), items: make(map[int64]*list.Element), order: list.New()}
+}
+
+func (c *lruCache) get(key int64) (int64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[key]
+	if !ok {
+		return 0, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*lruEntry).value, true
+}
+
+func (c *lruCache) put(key, value int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[key]; ok {
+		e.Value.(*lruEntry).value = value
+		c.order.MoveToFront(e)
+		return
+	}
+	if c.order.Len() >= c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry).key)
+	}
+	c.items[key] = c.order.PushFront(&lruEntry{key, value})
+}
+
+// shardedLRU splits the capacity over independent LRUs picked by key hash,
+// recency is only tracked within a shard
+type shardedLRU struct {
+	shards []*lruCache
+}
+
+func newShardedLRU(capacity int, shards int) *shardedLRU {
+	c := &shardedLRU{shards: make([]*lruCache, shards)}
+	for i := range c.shards {
+		c.shards[i] = newLRUCache(capacity / shards)
+	}
+	return c
+}
+
+func (c *shardedLRU) shard(key int64) *lruCache {
+	return c.shards[uint64(key)*0x9e3779b97f4a7c15>>32%uint64(len(c.shards))]
+}
+
+func (c *shardedLRU) get(key int64) (int64, bool) { return c.shard(key).get(key) }
+func (c *shardedLRU) put(key, value int64)        { c.shard(key).put(key, value) }
+
+// syncMapCache bounds a sync.Map with CLOCK eviction: hits only set a
+// referenced bit so reads never take a lock, and inserts sweep a ring of
+// keys under a mutex, giving referenced keys a second chance before evicting
+type syncMapCache struct {
+	m        sync.Map // int64 -> *clockEntry
+	mu       sync.Mutex
+	ring     []int64
+	hand     int
+	capacity int
+}
+
+type clockEntry struct {
+	value      atomic.Int64
+	referenced atomic.Bool
+}
+
+func (c *syncMapCache) get(key int64) (int64, bool) {
+	v, ok := c.m.Load(key)
+	if !ok {
+		return 0, false
+	}
+	e := v.(*clockEntry)
+	if !e.referenced.Load() {
+		e.referenced.Store(true)
+	}
+	return e.value.Load(), true
+}
+
+func (c *syncMapCache) put(key, value int64) {
+	e := &clockEntry{}
+	e.value.Store(value)
+	if v, loaded := c.m.LoadOrStore(key, e); loaded {
+		v.(*clockEntry).value.Store(value)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.ring) < c.capacity {
+		c.ring = append(c.ring, key)
+		return
+	}
+	for {
+		victim := c.ring[c.hand]
+		if v, ok := c.m.Load(victim); ok && v.(*clockEntry).referenced.Swap(false) {
+			c.hand = (c.hand + 1) % len(c.ring)
+			continue
+		}
+		c.m.Delete(victim)
+		c.ring[c.hand] = key
+		c.hand = (c.hand + 1) % len(c.ring)
+		return
+	}
+}
+
+// cache test has goroutines read through each bounded cache with zipfian
+// keys, filling it on every miss, and reports throughput, hit rate and the
+// p99 of a sampled subset of operations, sampling keeps the clock reads
+// from dominating the cheap hits
+func cacheTest(numGoroutines int, opsPerGoroutine int, keySpace int, capacity int) float64 {
+	caches := []struct {
+		name string
+		new  func() boundedCache
+	}{
+		{"single-lock LRU", func() boundedCache { return newLRUCache(capacity) }},
+		{"sharded LRU x16", func() boundedCache { return newShardedLRU(capacity, 16) }},
+		{"sync.Map", func() boundedCache { return &syncMapCache{capacity: capacity} }},
+	}
+	const sampleEvery = 16
+
+	totalTime := 0.0
+	for _, impl := range caches {
+		cache := impl.new()
+		var hits atomic.Int64
+		samples := make([][]time.Duration, numGoroutines)
+
+		start := time.Now()
+		var wg sync.WaitGroup
+		for g := 0; g < numGoroutines; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				rng := rand.New(rand.NewSource(int64(g)))
+				zipf := rand.NewZipf(rng, 1.1, 1, uint64(keySpace-1))
+				var hit int64
+				for i := 0; i < opsPerGoroutine; i++ {
+					key := int64(zipf.Uint64())
+					var opStart time.Time
+					if i%sampleEvery == 0 {
+						opStart = time.Now()
+					}
+					if _, ok := cache.get(key); ok {
+						hit++
+					} else {
+						cache.put(key, key*key)
+					}
+					if i%sampleEvery == 0 {
+						samples[g] = append(samples[g], time.Since(opStart))
+					}
+				}
+				hits.Add(hit)
+			}()
+		}
+		wg.Wait()
+		duration := time.Since(start)
+		totalTime += float64(duration.Nanoseconds()) / 1000000.0
+
+		var all []time.Duration
+		for _, s := range samples {
+			all = append(all, s...)
+		}
+		sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+		ops := numGoroutines * opsPerGoroutine
+		fmt.Fprintf(os.Stderr, "cache %-16s %d goroutines, %d keys, capacity %d: %.2f M ops/s, hit rate %.1f%%, p50 %d ns, p99 %d ns\n",
+			impl.name, numGoroutines, keySpace, capacity, float64(ops)/duration.Seconds()/1e6,
+			100*float64(hits.Load())/float64(ops), all[len(all)/2].Nanoseconds(), all[int(0.99*float64(len(all)-1))].Nanoseconds())
+	}
+	return totalTime
+}
+
+// mpmcRing is a bounded lock-free multi-producer multi-consumer queue, the
+// Vyukov design: every slot carries a sequence number telling producers and
+// consumers whose turn it is, so a CAS on the position is the only contention
+type mpmcRing struct {
+	mask       uint64
+	_          [56]byte
+	enqueuePos atomic.Uint64
+	_          [56]byte
+	dequeuePos atomic.Uint64
+	_          [56]byte
+	slots      []mpmcSlot
+}
+
+type mpmcSlot struct {
+	seq   atomic.Uint64
+	value int64
+}
+
+// newMPMCRing rounds capacity up to a power of two
+func newMPMCRing(capacity int) *mpmcRing {
+	size := 1
+	for size < capacity {
+		size <<= 1
+	}
+	r := &mpmcRing{mask: uint64(size - 1), slots: make([]mpmcSlot, size)}
+	for i := range r.slots {
+		r.slots[i].seq.Store(uint64(i))
+	}
+	return r
+}
+
+func (r *mpmcRing) tryEnqueue(value int64) bool {
+	pos := r.enqueuePos.Load()
+	for {
+		slot := &r.slots[pos&r.mask]
+		switch diff := int64(slot.seq.Load()) - int64(pos); {
+		case diff == 0:
+			if r.enqueuePos.CompareAndSwap(pos, pos+1) {
+				slot.value = value
+				slot.seq.Store(pos + 1)
+				return true
+			}
+			pos = r.enqueuePos.Load()
+		case diff < 0:
+			// the consumer a lap behind hasn't freed it, full
+			return false
+		default:
+			pos = r.enqueuePos.Load()
+		}
+	}
+}
+
+func (r *mpmcRing) tryDequeue() (int64, bool) {
+	pos := r.dequeuePos.Load()
+	for {
+		slot := &r.slots[pos&r.mask]
+		switch diff := int64(slot.seq.Load()) - int64(pos+1); {
+		case diff == 0:
+			if r.dequeuePos.CompareAndSwap(pos, pos+1) {
+				value := slot.value
+				slot.seq.Store(pos + r.mask + 1)
+				return value, true
+			}
+			pos = r.dequeuePos.Load()
+		case diff < 0:
+			// nothing published here yet, empty
+			return 0, false
+		default:
+			pos = r.dequeuePos.Load()
+		}
+	}
+}
+
+// enqueue and dequeue yield while full or empty, the ring has no parking
+func (r *mpmcRing) enqueue(value int64) {
+	for !r.tryEnqueue(value) {
+		runtime.Gosched()
+	}
+}
+
+func (r *mpmcRing) dequeue() int64 {
+	for {
+		if value, ok := r.tryDequeue(); ok {
+			return value
+		}
+		runtime.Gosched()
+	}
+}
+
+// ring vs channel test runs the same producer/consumer workload through the
+// lock-free ring and a buffered channel of the same capacity
+func ringVsChannelTest(messages int, capacity int) float64 {
+	shapes := [][2]int{{1, 1}, {2, 2}, {4, 4}, {1, 4}, {4, 1}}
+	queues := []struct {
+		name string
+		new  func() (send func(int64), recv func() int64)
+	}{
+		{"mpmc ring", func() (func(int64), func() int64) {
+			r := newMPMCRing(capacity)
+			return r.enqueue, r.dequeue
+		}},
+		{"channel", func() (func(int64), func() int64) {
+			ch := make(chan int64, capacity)
+			return func(v int64) { ch <- v }, func() int64 { return <-ch }
+		}},
+	}
+
+	totalTime := 0.0
+	fmt.Fprintf(os.Stderr, "%-12s", "M msgs/s")
+	for _, shape := range shapes {
+		fmt.Fprintf(os.Stderr, " %8s", fmt.Sprintf("%dp%dc", shape[0], shape[1]))
+	}
+	fmt.Fprintln(os.Stderr)
+
+	for _, queue := range queues {
+		fmt.Fprintf(os.Stderr, "%-12s", queue.name)
+		for _, shape := range shapes {
+			producers, consumers := shape[0], shape[1]
+			send, recv := queue.new()
+			// every count divides 4, so the shares come out even
+			total := messages / 4 * 4
+
+			start := time.Now()
+			var wg sync.WaitGroup
+			var checksum int64
+			for p := 0; p < producers; p++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < total/producers; i++ {
+						send(int64(i))
+					}
+				}()
+			}
+			for c := 0; c < consumers; c++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					var sum int64
+					for i := 0; i < total/consumers; i++ {
+						sum += recv()
+					}
+					atomic.AddInt64(&checksum, sum)
+				}()
+			}
+			wg.Wait()
+
+			duration := time.Since(start)
+			_ = atomic.LoadInt64(&checksum) // prevent optimization
+			totalTime += float64(duration.Nanoseconds()) / 1000000.0
+			fmt.Fprintf(os.Stderr, " %8.2f", float64(total)/duration.Seconds()/1e6)
+		}
+		fmt.Fprintln(os.Stderr)
+	}
+	return totalTime
+}
+
+// wakeupStats collects how long woken goroutines took to run after the
+// notification was sent
+type wakeupStats struct {
+	total atomic.Int64
+	max   atomic.Int64
+	count atomic.Int64
+}
+
+func (w *wakeupStats) add(latency time.Duration) {
+	w.total.Add(int64(latency))
+	w.count.Add(1)
+	for {
+		old := w.max.Load()
+		if int64(latency) <= old || w.max.CompareAndSwap(old, int64(latency)) {
+			return
+		}
+	}
+}
+
+// cond wakeup test wakes n parked goroutines, one per notification with
+// Signal against a token channel, and all of them with Broadcast against
+// closing a channel, for a few waiter counts
+func condWakeupTest(waiterCounts []int, rounds int) float64 {
+	totalTime := 0.0
+	report := func(name string, waiters int, stats *wakeupStats, duration time.Duration) {
+		totalTime += float64(duration.Nanoseconds()) / 1000000.0
+		fmt.Fprintf(os.Stderr, "%-24s %6d waiters: %8.0f rounds/s, wakeup mean %8.0f ns, max %10.0f ns\n", name, waiters,
+			float64(rounds)/duration.Seconds(), float64(stats.total.Load())/float64(max(stats.count.Load(), 1)), float64(stats.max.Load()))
+	}
+
+	for _, waiters := range waiterCounts {
+		// Signal: each round wakes one of the waiters, which acks before the next
+		{
+			var mu sync.Mutex
+			cond := sync.NewCond(&mu)
+			pending := 0
+			var sentAt time.Time
+			var stats wakeupStats
+			ack := make(chan struct{})
+			stop := false
+
+			for w := 0; w < waiters; w++ {
+				go func() {
+					for {
+						mu.Lock()
+						for pending == 0 && !stop {
+							cond.Wait()
+						}
+						if stop {
+							mu.Unlock()
+							return
+						}
+						pending--
+						stats.add(time.Since(sentAt))
+						mu.Unlock()
+						ack <- struct{}{}
+					}
+				}()
+			}
+
+			start := time.Now()
+			for r := 0; r < rounds; r++ {
+				mu.Lock()
+				pending++
+				sentAt = time.Now()
+				mu.Unlock()
+				cond.Signal()
+				<-ack
+			}
+			duration := time.Since(start)
+			mu.Lock()
+			stop = true
+			mu.Unlock()
+			cond.Broadcast()
+			report("cond Signal", waiters, &stats, duration)
+		}
+
+		// channel token: same handoff, the waiters all receive on one channel
+		{
+			tokens := make(chan time.Time)
+			ack := make(chan struct{})
+			var stats wakeupStats
+			for w := 0; w < waiters; w++ {
+				go func() {
+					for sent := range tokens {
+						stats.add(time.Since(sent))
+						ack <- struct{}{}
+					}
+				}()
+			}
+
+			start := time.Now()
+			for r := 0; r < rounds; r++ {
+				tokens <- time.Now()
+				<-ack
+			}
+			duration := time.Since(start)
+			close(tokens)
+			report("channel token", waiters, &stats, duration)
+		}
+
+		// Broadcast: every round wakes all waiters, the next starts once all acked
+		{
+			var mu sync.Mutex
+			cond := sync.NewCond(&mu)
+			generation := 0
+			var sentAt time.Time
+			var stats wakeupStats
+			var acked sync.WaitGroup
+
+			var ready sync.WaitGroup
+			ready.Add(waiters)
+			for w := 0; w < waiters; w++ {
+				go func() {
+					seen := 0
+					ready.Done()
+					for r := 0; r < rounds; r++ {
+						mu.Lock()
+						for generation == seen {
+							cond.Wait()
+						}
+						seen = generation
+						stats.add(time.Since(sentAt))
+						mu.Unlock()
+						acked.Done()
+					}
+				}()
+			}
+			ready.Wait()
+
+			start := time.Now()
+			for r := 0; r < rounds; r++ {
+				acked.Add(waiters)
+				mu.Lock()
+				generation++
+				sentAt = time.Now()
+				mu.Unlock()
+				cond.Broadcast()
+				acked.Wait()
+			}
+			report("cond Broadcast", waiters, &stats, time.Since(start))
+		}
+
+		// closing a channel per round wakes everyone the same way
+		{
+			channels := make([]chan struct{}, rounds)
+			sentAt := make([]time.Time, rounds)
+			for r := range channels {
+				channels[r] = make(chan struct{})
+			}
+			var stats wakeupStats
+			var acked sync.WaitGroup
+			for w := 0; w < waiters; w++ {
+				go func() {
+					for r := 0; r < rounds; r++ {
+						<-channels[r]
+						stats.add(time.Since(sentAt[r]))
+						acked.Done()
+					}
+				}()
+			}
+
+			start := time.Now()
+			for r := 0; r < rounds; r++ {
+				acked.Add(waiters)
+				sentAt[r] = time.Now()
+				close(channels[r])
+				acked.Wait()
+			}
+			report("channel close", waiters, &stats, time.Since(start))
+		}
+	}
+	return totalTime
+}
+
+// lockedCounter is one way of guarding a shared counter
+type lockedCounter struct {
+	name string
+	op   func(i int) int64
+}
+
+// lockedCounters builds the guards, each rwmutex one writes for 1 in every
+// writeEveryRW operations and reads under RLock the rest of the time
+func lockedCounters(writeEveryRW []int) []lockedCounter {
+	var mu sync.Mutex
+	var rw sync.RWMutex
+	var plain, atomicValue int64
+
+	counters := []lockedCounter{
+		{"mutex", func(int) int64 {
+			mu.Lock()
+			plain++
+			v := plain
+			mu.Unlock()
+			return v
+		}},
+	}
+	for _, every := range writeEveryRW {
+		name := fmt.Sprintf("rwmutex %d%% read", 100-100/every)
+		counters = append(counters, lockedCounter{name, func(i int) int64 {
+			if i%every == 0 {
+				rw.Lock()
+				plain++
+				v := plain
+				rw.Unlock()
+				return v
+			}
+			rw.RLock()
+			v := plain
+			rw.RUnlock()
+			return v
+		}})
+	}
+	counters = append(counters, lockedCounter{"atomic", func(int) int64 {
+		return atomic.AddInt64(&atomicValue, 1)
+	}})
+	return counters
+}
+
+// lock contention test has goroutines hammer one shared counter through each
+// kind of guard, doubling the goroutine count up to maxGoroutines
+func lockContentionTest(maxGoroutines int, opsPerGoroutine int) float64 {
+	var counts []int
+	for n := 1; n <= maxGoroutines; n *= 2 {
+		counts = append(counts, n)
+	}
+
+	totalTime := 0.0
+	fmt.Fprintf(os.Stderr, "%-20s", "M ops/s")
+	for _, n := range counts {
+		fmt.Fprintf(os.Stderr, " %8s", fmt.Sprintf("%dg", n))
+	}
+	fmt.Fprintln(os.Stderr)
+
+	for _, counter := range lockedCounters([]int{10, 2}) {
+		fmt.Fprintf(os.Stderr, "%-20s", counter.name)
+		for _, n := range counts {
+			start := time.Now()
+
+			var wg sync.WaitGroup
+			var checksum int64
+			for g := 0; g < n; g++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					var sum int64
+					for i := 0; i < opsPerGoroutine; i++ {
+						sum += counter.op(i)
+					}
+					atomic.AddInt64(&checksum, sum)
+				}()
+			}
+			wg.Wait()
+
+			duration := time.Since(start)
+			_ = atomic.LoadInt64(&checksum) // prevent optimization
+			totalTime += float64(duration.Nanoseconds()) / 1000000.0
+			fmt.Fprintf(os.Stderr, " %8.2f", float64(n*opsPerGoroutine)/duration.Seconds()/1e6)
+		}
+		fmt.Fprintln(os.Stderr)
+	}
+	return totalTime
+}
+
+// criticalWork stands in for whatever is computed from the old value before
+// the new one can be published, it's redone on every CAS retry
+func criticalWork(old uint64, units int) uint64 {
+	mix := old
+	for i := 0; i < units; i++ {
+		mix = mix*6364136223846793005 + 1442695040888963407
+	}
+	return mix
+}
+
+// cas vs mutex test increments one shared counter with a CompareAndSwap retry
+// loop and under a mutex, sweeping the goroutine count and how much work
+// sits inside the update. CAS never blocks but throws its work away on every
+// failed swap, so it loses ground as both numbers grow
+func casVsMutexTest(maxGoroutines int, opsPerGoroutine int, workUnits []int) float64 {
+	var counts []int
+	for n := 1; n <= maxGoroutines; n *= 2 {
+		counts = append(counts, n)
+	}
+
+	fmt.Fprintf(os.Stderr, "%-20s", "M ops/s")
+	for _, n := range counts {
+		fmt.Fprintf(os.Stderr, " %8s", fmt.Sprintf("%dg", n))
+	}
+	fmt.Fprintf(os.Stderr, "   cas retries/op at %dg\n", counts[len(counts)-1])
+
+	totalTime := 0.0
+	for _, units := range workUnits {
+		for _, useCAS := range []bool{true, false} {
+			name := fmt.Sprintf("mutex work=%d", units)
+			if useCAS {
+				name = fmt.Sprintf("cas work=%d", units)
+			}
+			fmt.Fprintf(os.Stderr, "%-20s", name)
+
+			var retries atomic.Int64
+			for _, n := range counts {
+				var value atomic.Uint64
+				var mu sync.Mutex
+				var plain uint64
+				var sink atomic.Uint64
+				retries.Store(0)
+
+				start := time.Now()
+				var wg sync.WaitGroup
+				for g := 0; g < n; g++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						var mix uint64
+						var failed int64
+						for i := 0; i < opsPerGoroutine; i++ {
+							if useCAS {
+								for {
+									old := value.Load()
+									mix += criticalWork(old, units)
+									if value.CompareAndSwap(old, old+1) {
+										break
+									}
+									failed++
+								}
+							} else {
+								mu.Lock()
+								mix += criticalWork(plain, units)
+								plain++
+								mu.Unlock()
+							}
+						}
+						retries.Add(failed)
+						sink.Add(mix)
+					}()
+				}
+				wg.Wait()
+				duration := time.Since(start)
+				totalTime += float64(duration.Nanoseconds()) / 1000000.0
+
+				want := uint64(n * opsPerGoroutine)
+				if got := value.Load() + plain; got != want {
+					fmt.Fprintf(os.Stderr, "\n%s at %dg counted %d, want %d\n", name, n, got, want)
+				}
+				_ = sink.Load() // prevent optimization
+				fmt.Fprintf(os.Stderr, " %8.2f", float64(want)/duration.Seconds()/1e6)
+			}
+			if useCAS {
+				fmt.Fprintf(os.Stderr, "   %.3f", float64(retries.Load())/float64(counts[len(counts)-1]*opsPerGoroutine))
+			}
+			fmt.Fprintln(os.Stderr)
+		}
+	}
+	return totalTime
+}
+
+// spinLock is a test-and-test-and-set lock that never parks. with yield set
+// it calls runtime.Gosched after every spinTries failed attempts, otherwise
+// it burns its whole time slice when the holder isn't running
+type spinLock struct {
+	state atomic.Int32
+	yield bool
+}
+
+const spinTries = 64
+
+func (l *spinLock) Lock() {
+	for tries := 0; ; tries++ {
+		if l.state.Load() == 0 && l.state.CompareAndSwap(0, 1) {
+			return
+		}
+		if l.yield && tries%spinTries == spinTries-1 {
+			runtime.Gosched()
+		}
+	}
+}
+
+func (l *spinLock) Unlock() { l.state.Store(0) }
+
+// spinlock test guards a very short critical section with sync.Mutex and the
+// spin locks at goroutine counts below, at and above GOMAXPROCS. spinning wins
+// while every goroutine has a P, since the lock is released long before a
+// mutex waiter would have parked. past GOMAXPROCS a preempted holder leaves
+// the pure spinner burning its slice until preemption, which is where it
+// collapses, and yielding only softens that
+func spinlockTest(counts []int, opsPerGoroutine int) float64 {
+	// counts derived from GOMAXPROCS collapse onto each other on small machines
+	sort.Ints(counts)
+	unique := counts[:1]
+	for _, n := range counts[1:] {
+		if n != unique[len(unique)-1] {
+			unique = append(unique, n)
+		}
+	}
+	counts = unique
+
+	locks := []struct {
+		name string
+		new  func() sync.Locker
+	}{
+		{"sync.Mutex", func() sync.Locker { return &sync.Mutex{} }},
+		{"spin", func() sync.Locker { return &spinLock{} }},
+		{"spin+Gosched", func() sync.Locker { return &spinLock{yield: true} }},
+	}
+
+	procs := runtime.GOMAXPROCS(0)
+	fmt.Fprintf(os.Stderr, "%-20s", fmt.Sprintf("M ops/s (%d procs)", procs))
+	for _, n := range counts {
+		label := fmt.Sprintf("%dg", n)
+		if n > procs {
+			label += "*"
+		}
+		fmt.Fprintf(os.Stderr, " %8s", label)
+	}
+	fmt.Fprintln(os.Stderr, "   * oversubscribed")
+
+	totalTime := 0.0
+	for _, lock := range locks {
+		fmt.Fprintf(os.Stderr, "%-20s", lock.name)
+		for _, n := range counts {
+			mu := lock.new()
+			var counter, checksum int64
+
+			start := time.Now()
+			var wg sync.WaitGroup
+			for g := 0; g < n; g++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					var sum int64
+					for i := 0; i < opsPerGoroutine; i++ {
+						mu.Lock()
+						counter++
+						sum += counter
+						mu.Unlock()
+					}
+					atomic.AddInt64(&checksum, sum)
+				}()
+			}
+			wg.Wait()
+			duration := time.Since(start)
+			totalTime += float64(duration.Nanoseconds()) / 1000000.0
+
+			if counter != int64(n*opsPerGoroutine) {
+				fmt.Fprintf(os.Stderr, "\n%s at %dg counted %d, want %d\n", lock.name, n, counter, n*opsPerGoroutine)
+			}
+			fmt.Fprintf(os.Stderr, " %8.2f", float64(n*opsPerGoroutine)/duration.Seconds()/1e6)
+		}
+		fmt.Fprintln(os.Stderr)
+	}
+	return totalTime
+}
+
+// spinWork burns roughly units of cpu work
+func spinWork(units int) int64 {
+	var work int64
+	for j := 0; j < units*100; j++ {
+		work += int64(j * j)
+	}
+	return work
+}
+
+// skewed task test gives the worker pool and a static split the same tasks,
+// sizes drawn from a pareto distribution so a few tasks dwarf the rest, the
+// static split hands each worker a fixed contiguous slice up front while the
+// pool lets idle workers pick up whatever is left
+func skewedTaskTest(numWorkers int, numTasks int) float64 {
+	rng := rand.New(rand.NewSource(42))
+	sizes := make([]int, numTasks)
+	totalUnits := 0
+	for i := range sizes {
+		// pareto with alpha 1.2, capped so one task can't be the whole run
+		sizes[i] = min(int(10/math.Pow(1-rng.Float64(), 1/1.2)), 20000)
+		totalUnits += sizes[i]
+	}
+
+	// imbalance is the makespan against a perfect split of the summed work
+	// over the cpus actually available, 1.0 means every worker finished together
+	var taskTime atomic.Int64
+	parallelism := min(numWorkers, runtime.GOMAXPROCS(0))
+	report := func(name string, duration time.Duration) {
+		ideal := float64(taskTime.Load()) / float64(parallelism)
+		fmt.Fprintf(os.Stderr, "skewed tasks %s: %d workers, %d tasks, %.3f ms, imbalance %.2fx\n",
+			name, numWorkers, numTasks, float64(duration.Nanoseconds())/1000000.0, float64(duration.Nanoseconds())/ideal)
+	}
+	timed := func(units int) int64 {
+		start := time.Now()
+		work := spinWork(units)
+		taskTime.Add(int64(time.Since(start)))
+		return work
+	}
+
+	start := time.Now()
+	var checksum int64
+	var wg sync.WaitGroup
+	per := (numTasks + numWorkers - 1) / numWorkers
+	for w := 0; w < numWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var sum int64
+			for _, units := range sizes[min(w*per, numTasks):min((w+1)*per, numTasks)] {
+				sum += timed(units)
+			}
+			atomic.AddInt64(&checksum, sum)
+		}()
+	}
+	wg.Wait()
+	static := time.Since(start)
+	report("static split", static)
+
+	taskTime.Store(0)
+	start = time.Now()
+	pool := NewWorkerPool(numWorkers)
+	for _, units := range sizes {
+		pool.Submit(context.Background(), func() {
+			atomic.AddInt64(&checksum, timed(units))
+		})
+	}
+	pool.Wait()
+	pool.Close()
+	pooled := time.Since(start)
+	report("worker pool", pooled)
+
+	_ = atomic.LoadInt64(&checksum) // prevent optimization
+	return float64((static + pooled).Nanoseconds()) / 1000000.0
+}
+
+// mapPartial is what the map phase produces for one chunk, a bucketed sum
+// and count that reducers merge
+type mapPartial struct {
+	sums   [16]float64
+	counts [16]int64
+}
+
+func (p *mapPartial) merge(other *mapPartial) {
+	for b := range p.sums {
+		p.sums[b] += other.sums[b]
+		p.counts[b] += other.counts[b]
+	}
+}
+
+func mapChunk(data []float64) *mapPartial {
+	p := &mapPartial{}
+	for _, x := range data {
+		b := int(x * 16)
+		p.sums[b] += math.Sqrt(x) * math.Log1p(x)
+		p.counts[b]++
+	}
+	return p
+}
+
+// treeReduce merges partials pairwise, every level's merges run in parallel
+func treeReduce(partials []*mapPartial) *mapPartial {
+	for len(partials) > 1 {
+		half := (len(partials) + 1) / 2
+		var wg sync.WaitGroup
+		for i := 0; i+half < len(partials); i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				partials[i].merge(partials[i+half])
+			}()
+		}
+		wg.Wait()
+		partials = partials[:half]
+	}
+	return partials[0]
+}
+
+// map reduce test splits data across workers for the map phase, either one
+// static slice per worker or chunks handed out from an atomic cursor, then
+// merges the partials with a parallel tree reduction or by streaming them to
+// a single reducer over a channel. every mix runs at 1, 2, 4 ... workers
+func mapReduceTest(elements int, maxWorkers int) float64 {
+	rng := rand.New(rand.NewSource(7))
+	data := make([]float64, elements)
+	for i := range data {
+		data[i] = rng.Float64()
+	}
+	want := mapChunk(data)
+
+	chunkings := []struct {
+		name  string
+		chunk func(workers int) int
+	}{
+		{"static", func(workers int) int { return (elements + workers - 1) / workers }},
+		{"dynamic 4k", func(int) int { return 4096 }},
+		{"dynamic 64k", func(int) int { return 64 * 1024 }},
+	}
+
+	var counts []int
+	for n := 1; n <= maxWorkers; n *= 2 {
+		counts = append(counts, n)
+	}
+	fmt.Fprintf(os.Stderr, "%-34s", fmt.Sprintf("map reduce ms, %d elements", elements))
+	for _, n := range counts {
+		fmt.Fprintf(os.Stderr, " %8s", fmt.Sprintf("%dw", n))
+	}
+	fmt.Fprintln(os.Stderr)
+
+	totalTime := 0.0
+	for _, chunking := range chunkings {
+		for _, tree := range []bool{true, false} {
+			name := chunking.name + " + channel"
+			if tree {
+				name = chunking.name + " + tree"
+			}
+			fmt.Fprintf(os.Stderr, "  %-32s", name)
+
+			for _, workers := range counts {
+				chunkSize := chunking.chunk(workers)
+				numChunks := (elements + chunkSize - 1) / chunkSize
+				var cursor atomic.Int64
+				next := func() (int, bool) {
+					c := int(cursor.Add(1) - 1)
+					return c, c < numChunks
+				}
+
+				start := time.Now()
+				var result *mapPartial
+				var wg sync.WaitGroup
+				if tree {
+					partials := make([]*mapPartial, numChunks)
+					for w := 0; w < workers; w++ {
+						wg.Add(1)
+						go func() {
+							defer wg.Done()
+							for c, ok := next(); ok; c, ok = next() {
+								partials[c] = mapChunk(data[c*chunkSize : min((c+1)*chunkSize, elements)])
+							}
+						}()
+					}
+					wg.Wait()
+					result = treeReduce(partials)
+				} else {
+					results := make(chan *mapPartial, workers)
+					for w := 0; w < workers; w++ {
+						wg.Add(1)
+						go func() {
+							defer wg.Done()
+							for c, ok := next(); ok; c, ok = next() {
+								results <- mapChunk(data[c*chunkSize : min((c+1)*chunkSize, elements)])
+							}
+						}()
+					}
+					go func() {
+						wg.Wait()
+						close(results)
+					}()
+					result = &mapPartial{}
+					for p := range results {
+						result.merge(p)
+					}
+				}
+				duration := time.Since(start)
+				totalTime += float64(duration.Nanoseconds()) / 1000000.0
+
+				for b := range want.sums {
+					if result.counts[b] != want.counts[b] || math.Abs(result.sums[b]-want.sums[b]) > 1e-9*math.Abs(want.sums[b]) {
+						fmt.Fprintf(os.Stderr, "\nmap reduce %s with %d workers got a wrong bucket %d\n", name, workers, b)
+						break
+					}
+				}
+				fmt.Fprintf(os.Stderr, " %8.3f", float64(duration.Nanoseconds())/1000000.0)
+			}
+			fmt.Fprintln(os.Stderr)
+		}
+	}
+	return totalTime
+}
+
+// startTCPBackend serves fixed 8 byte requests on an ephemeral port, echoing
+// the id back after a short exponential delay, with slowShare of requests
+// taking slowDelay instead so the coordinator's deadline actually trips
+func startTCPBackend(seed int64, meanDelay time.Duration, slowShare float64, slowDelay time.Duration) (net.Listener, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for conn := 0; ; conn++ {
+			c, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				rng := rand.New(rand.NewSource(seed*1000 + int64(conn)))
+				var buf [8]byte
+				for {
+					if _, err := io.ReadFull(c, buf[:]); err != nil {
+						return
+					}
+					delay := time.Duration(rng.ExpFloat64() * float64(meanDelay))
+					if rng.Float64() < slowShare {
+						delay = slowDelay
+					}
+					time.Sleep(delay)
+					if _, err := c.Write(buf[:]); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return listener, nil
+}
+
+// scatter gather test has several coordinators, each with one connection to
+// every backend, send a request to all backends at once and wait for every
+// answer until a shared deadline. a gather that misses the deadline counts
+// as timed out, and the connections that didn't answer are redialed since
+// their late reply would otherwise be read by the next gather
+func scatterGatherTest(numBackends int, coordinators int, gathersPerCoordinator int, deadline time.Duration) float64 {
+	var addrs []string
+	for b := 0; b < numBackends; b++ {
+		listener, err := startTCPBackend(int64(b), 200*time.Microsecond, 0.01, 4*deadline)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Scatter gather backend failed -> %v\n", err)
+			return 0.0
+		}
+		defer listener.Close()
+		addrs = append(addrs, listener.Addr().String())
+	}
+
+	var completed, timedOut, answered, redials atomic.Int64
+	var firstErr atomic.Value
+	latencies := make([][]time.Duration, coordinators)
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for c := 0; c < coordinators; c++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conns := make([]net.Conn, numBackends)
+			defer func() {
+				for _, conn := range conns {
+					if conn != nil {
+						conn.Close()
+					}
+				}
+			}()
+
+			ok := make([]bool, numBackends)
+			for g := 0; g < gathersPerCoordinator; g++ {
+				gatherStart := time.Now()
+				due := gatherStart.Add(deadline)
+				var scatter sync.WaitGroup
+				for b := range conns {
+					scatter.Add(1)
+					go func() {
+						defer scatter.Done()
+						ok[b] = false
+						if conns[b] == nil {
+							conn, err := net.Dial("tcp", addrs[b])
+							if err != nil {
+								firstErr.CompareAndSwap(nil, err)
+								return
+							}
+							conns[b] = conn
+						}
+						conn := conns[b]
+						conn.SetDeadline(due)
+						var buf [8]byte
+						binary.LittleEndian.PutUint64(buf[:], uint64(g))
+						if _, err := conn.Write(buf[:]); err != nil {
+							return
+						}
+						if _, err := io.ReadFull(conn, buf[:]); err != nil || binary.LittleEndian.Uint64(buf[:]) != uint64(g) {
+							return
+						}
+						ok[b] = true
+					}()
+				}
+				scatter.Wait()
+
+				all := true
+				for b, answeredInTime := range ok {
+					if answeredInTime {
+						answered.Add(1)
+						continue
+					}
+					all = false
+					if conns[b] != nil {
+						conns[b].Close()
+						conns[b] = nil
+						redials.Add(1)
+					}
+				}
+				if all {
+					completed.Add(1)
+					latencies[c] = append(latencies[c], time.Since(gatherStart))
+				} else {
+					timedOut.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	duration := time.Since(start)
+
+	gathers := coordinators * gathersPerCoordinator
+	fmt.Fprintf(os.Stderr, "scatter gather: %d backends, %d coordinators, deadline %v, %.0f complete gathers/s, %d of %d timed out (%.2f%%), %.1f%% of backend calls answered, %d redials\n",
+		numBackends, coordinators, deadline, float64(completed.Load())/duration.Seconds(), timedOut.Load(), gathers,
+		100*float64(timedOut.Load())/float64(gathers), 100*float64(answered.Load())/float64(gathers*numBackends), redials.Load())
+	if err := firstErr.Load(); err != nil {
+		fmt.Fprintf(os.Stderr, "  first dial error -> %v\n", err)
+	}
+
+	var all []time.Duration
+	for _, l := range latencies {
+		all = append(all, l...)
+	}
+	if len(all) > 0 {
+		sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+		percentile := func(p float64) float64 {
+			return float64(all[int(p*float64(len(all)-1))].Microseconds()) / 1000.0
+		}
+		fmt.Fprintf(os.Stderr, "  complete gather latency: p50 %.3f ms, p99 %.3f ms, max %.3f ms\n",
+			percentile(0.50), percentile(0.99), percentile(1))
+	}
+	return float64(duration.Nanoseconds()) / 1000000.0
+}
+
+// pipelineItem flows through every stage, queued is when it was last sent
+type pipelineItem struct {
+	line     string
+	id       int
+	value    float64
+	category int
+	queued   time.Time
+}
+
+// pipeline test pushes generated lines through parse, transform, filter and
+// aggregate stages joined by channels, the middle stages each run workers
+// goroutines, and every stage adds up how long items sat in its input queue
+func pipelineTest(items int, workers int) float64 {
+	stageNames := []string{"parse", "transform", "filter", "aggregate"}
+	waits := make([]atomic.Int64, len(stageNames))
+	received := make([]atomic.Int64, len(stageNames))
+
+	// stage runs fn over in with n goroutines and closes out once all are done,
+	// fn returning false drops the item
+	stage := func(index int, n int, in <-chan pipelineItem, fn func(*pipelineItem) bool) <-chan pipelineItem {
+		out := make(chan pipelineItem, 256)
+		var wg sync.WaitGroup
+		for w := 0; w < n; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				var waited time.Duration
+				var count int64
+				for item := range in {
+					waited += time.Since(item.queued)
+					count++
+					if fn(&item) {
+						item.queued = time.Now()
+						out <- item
+					}
+				}
+				waits[index].Add(int64(waited))
+				received[index].Add(count)
+			}()
+		}
+		go func() {
+			wg.Wait()
+			close(out)
+		}()
+		return out
+	}
+
+	start := time.Now()
+
+	source := make(chan pipelineItem, 256)
+	go func() {
+		for i := 0; i < items; i++ {
+			source <- pipelineItem{line: fmt.Sprintf("%d,%d.%02d,%d", i, i%1000, i%100, i%7), queued: time.Now()}
+		}
+		close(source)
+	}()
+
+	parsed := stage(0, workers, source, func(item *pipelineItem) bool {
+		fields := strings.Split(item.line, ",")
+		id, err1 := strconv.Atoi(fields[0])
+		value, err2 := strconv.ParseFloat(fields[1], 64)
+		category, err3 := strconv.Atoi(fields[2])
+		if err1 != nil || err2 != nil || err3 != nil {
+			return false
+		}
+		item.id, item.value, item.category = id, value, category
+		return true
+	})
+	transformed := stage(1, workers, parsed, func(item *pipelineItem) bool {
+		item.value = math.Sqrt(item.value*1.07+float64(item.id%13)) * 10
+		return true
+	})
+	filtered := stage(2, workers, transformed, func(item *pipelineItem) bool {
+		return item.category != 3
+	})
+
+	// the aggregate is the sink, one goroutine owns the totals
+	var totals [7]float64
+	kept := 0
+	var waited time.Duration
+	for item := range filtered {
+		waited += time.Since(item.queued)
+		totals[item.category] += item.value
+		kept++
+	}
+	waits[3].Add(int64(waited))
+	received[3].Add(int64(kept))
+
+	duration := time.Since(start)
+	_ = totals // prevent optimization
+
+	fmt.Fprintf(os.Stderr, "pipeline: %d items, %d workers per stage, %.0f items/s, %d aggregated\n",
+		items, workers, float64(items)/duration.Seconds(), kept)
+	for i, name := range stageNames {
+		fmt.Fprintf(os.Stderr, "pipeline %-10s %8d items, queue wait %8.0f ns/item\n", name, received[i].Load(),
+			float64(waits[i].Load())/float64(max(received[i].Load(), 1)))
+	}
+	return float64(duration.Nanoseconds()) / 1000000.0
+}
+
+// namedTest lets main run the sub-tests in order and rerun them for the scaling report
+type namedTest struct {
+	name string
+	run  func() float64
+}
+
+// scalingProcs returns 1, 2, 4, ... up to maxProcs, always ending on maxProcs itself
+func scalingProcs(maxProcs int) []int {
+	maxProcs = max(maxProcs, 1)
+	var procs []int
+	for p := 1; p < maxProcs; p *= 2 {
+		procs = append(procs, p)
+	}
+	return append(procs, maxProcs)
+}
+
+// scalingReport reruns each test at every GOMAXPROCS in procs. speedup is the
+// time at 1 proc over the time at p procs, efficiency is speedup divided by p,
+// so 100% means the test scaled perfectly and anything under it is overhead
+// or serial work. the tests' own detail lines are muted while they rerun
+func scalingReport(tests []namedTest, procs []int) {
+	previous := runtime.GOMAXPROCS(0)
+	defer runtime.GOMAXPROCS(previous)
+
+	stderr := os.Stderr
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		fmt.Fprintf(stderr, "Scaling report skipped -> %v\n", err)
+		return
+	}
+	defer devNull.Close()
+
+	fmt.Fprintf(stderr, "GOMAXPROCS scaling (cpus %d, procs %v):\n", runtime.NumCPU(), procs)
+	for _, test := range tests {
+		times := make([]float64, len(procs))
+		os.Stderr = devNull
+		for i, p := range procs {
+			runtime.GOMAXPROCS(p)
+			runtime.GC()
+			times[i] = test.run()
+		}
+		os.Stderr = stderr
+
+		fmt.Fprintf(stderr, "  %s:\n", test.name)
+		for i, p := range procs {
+			speedup := times[0] / max(times[i], 1e-9)
+			fmt.Fprintf(stderr, "    procs %3d  %10.3f ms  speedup %5.2fx  efficiency %5.1f%%\n",
+				p, times[i], speedup, 100*speedup/float64(p))
+		}
+	}
+}
+
+// goroutineStacks dumps every goroutine's stack keyed by its "goroutine N" header
+func goroutineStacks() map[string]string {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	stacks := make(map[string]string)
+	for _, stack := range strings.Split(string(buf), "\n\n") {
+		id, _, _ := strings.Cut(stack, " [")
+		stacks[id] = strings.TrimSpace(stack)
+	}
+	return stacks
+}
+
+// leakCheck returns how many goroutines a test left behind compared with the
+// count before it started. goroutines that are on their way out get a short
+// grace period, anything still running after that is counted as leaked
+func leakCheck(before int) int {
+	deadline := time.Now().Add(200 * time.Millisecond)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	return max(runtime.NumGoroutine()-before, 0)
+}
+
+// runTests runs every test once, checking after each one for goroutines it
+// leaked. with dumpStacks the stacks of goroutines that weren't there before
+// the test are printed too. the leak count is cumulative, so a test only
+// gets blamed for what it added itself
+func runTests(tests []namedTest, dumpStacks bool) float64 {
+	totalTime := 0.0
+	leakedBy := make(map[string]int)
+	for _, test := range tests {
+		before := runtime.NumGoroutine()
+		var baseline map[string]string
+		if dumpStacks {
+			baseline = goroutineStacks()
+		}
+
+		totalTime += test.run()
+
+		leaked := leakCheck(before)
+		if leaked == 0 {
+			continue
+		}
+		leakedBy[test.name] = leaked
+		fmt.Fprintf(os.Stderr, "Goroutine leak: %s left %d goroutines running\n", test.name, leaked)
+		if dumpStacks {
+			for id, stack := range goroutineStacks() {
+				if _, ok := baseline[id]; !ok {
+					fmt.Fprintf(os.Stderr, "%s\n\n", stack)
+				}
+			}
+		}
+	}
+
+	if len(leakedBy) > 0 {
+		fmt.Fprintf(os.Stderr, "Goroutine leaks in %d tests:", len(leakedBy))
+		for _, test := range tests {
+			if n, ok := leakedBy[test.name]; ok {
+				fmt.Fprintf(os.Stderr, " %s=%d", test.name, n)
+			}
+		}
+		fmt.Fprintln(os.Stderr)
+	}
+	return totalTime
+}
+
+func main() {
+	targetURL := flag.String("target-url", "", "run the http test against this url instead of the built-in server")
+	serverLatency := flag.Duration("server-latency", 0, "how long the built-in server waits before answering")
+	httpConcurrency := flag.Int("http-concurrency", 0, "http requests in flight at once, 0 sends them all at once")
+	postRatio := flag.Float64("http-post-ratio", 0, "share of http requests sent as POST, between 0 and 1")
+	payloadBytes := flag.Int("http-payload", 1024, "POST body size in bytes")
+	keepAlive := flag.Bool("http-keepalive", true, "reuse http connections, false dials one per request")
+	pipelineWorkers := flag.Int("pipeline-workers", 2, "goroutines per middle stage in the pipeline test run with -extended")
+	scaling := flag.Bool("scaling", false, "rerun every test at GOMAXPROCS 1..N and report speedup and efficiency")
+	scalingMax := flag.Int("scaling-max", runtime.NumCPU(), "highest GOMAXPROCS tried by -scaling")
+	leakStacks := flag.Bool("leak-stacks", false, "dump the stacks of goroutines a test leaked")
+	extended := flag.Bool("extended", false, "also run the go-only tests, reported on stderr and kept out of the total")
+	flag.Parse()
+	if *payloadBytes < 0 {
+		fmt.Fprintf(os.Stderr, "-http-payload must not be negative, got %d\n", *payloadBytes)
+		os.Exit(1)
+	}
+
+	scaleFactor := 1
+
+	if flag.NArg() > 0 {
+		if factor, err := strconv.Atoi(flag.Arg(0)); err == nil && factor > 0 {
+			scaleFactor = factor
+		} else {
+			fmt.Fprintf(os.Stderr, "Invalid scale factor. Using default 1.\n")
+		}
+	}
+
+	url := *targetURL
+	if url == "" {
+		server := startTestServer(*serverLatency)
+		defer server.Close()
+		url = server.URL + "/fast"
+	}
+
+	tests := []namedTest{
+		{"parallel http", func() float64 {
+			httpTime, err := parallelHttpTest(httpLoadConfig{
+				requests:     50 * scaleFactor,
+				concurrency:  *httpConcurrency,
+				postRatio:    min(max(*postRatio, 0), 1),
+				payloadBytes: *payloadBytes,
+				keepAlive:    *keepAlive,
+			}, url)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "parallel http test failed -> %v\n", err)
+				os.Exit(1)
+			}
+			return httpTime
+		}},
+		{"producer consumer", func() float64 { return producerConsumerTest(4, 1000*scaleFactor) }},
+		{"parallel math", func() float64 { return parallelMathTest(4, 100*scaleFactor) }},
+		{"async file", func() float64 { return asyncFileTest(20*scaleFactor, 4) }},
+		{"thread pool", func() float64 { return threadPoolTest(8, 500*scaleFactor) }},
+	}
+
+	// the other languages don't run these, they report on stderr only
+	extendedTests := []namedTest{
+		{"lock contention", func() float64 { return lockContentionTest(max(8, runtime.NumCPU()), 200000*scaleFactor) }},
+		{"channel sweep", func() float64 { return channelSweepTest(1000000*scaleFactor, 4) }},
+		{"fan in/out", func() float64 { return fanInOutTest(64, 50000*scaleFactor) }},
+		{"goroutine spawn", func() float64 { return goroutineSpawnTest(200000*scaleFactor, 10000) }},
+		{"errgroup", func() float64 { return errgroupTest(4, 20000*scaleFactor) }},
+		{"semaphore", func() float64 { return semaphoreTest(64, 4, 20000*scaleFactor) }},
+		{"concurrent map", func() float64 { return concurrentMapTest(max(8, runtime.NumCPU()), 100000*scaleFactor, 10000) }},
+		{"skewed task", func() float64 { return skewedTaskTest(max(4, runtime.NumCPU()), 5000*scaleFactor) }},
+		{"pipeline", func() float64 { return pipelineTest(200000*scaleFactor, max(*pipelineWorkers, 1)) }},
+		{"ring vs channel", func() float64 { return ringVsChannelTest(1000000*scaleFactor, 1024) }},
+		{"cond wakeup", func() float64 { return condWakeupTest([]int{1, 16, 256}, 2000*scaleFactor) }},
+		{"join", func() float64 { return joinTest(200000 * scaleFactor) }},
+		{"timer", func() float64 { return timerTest(8, 50000*scaleFactor) }},
+		{"futures", func() float64 { return futureTest(2000*scaleFactor, 32, 32) }},
+		{"actors", func() float64 { return actorTest(4096, 64, 10000*scaleFactor) }},
+		{"lazy init", func() float64 { return onceTest(max(8, runtime.NumCPU()), 2000000*scaleFactor) }},
+		{"cas vs mutex", func() float64 { return casVsMutexTest(max(8, runtime.NumCPU()), 100000*scaleFactor, []int{0, 16, 128}) }},
+		{"map reduce", func() float64 { return mapReduceTest(2000000*scaleFactor, max(8, runtime.NumCPU())) }},
+		{"scatter gather", func() float64 { return scatterGatherTest(8, 8, 200*scaleFactor, 5*time.Millisecond) }},
+		{"rate limit", func() float64 {
+			return rateLimitTest(4, []float64{2000, 20000}, []int{1, 100}, time.Duration(scaleFactor)*100*time.Millisecond)
+		}},
+		{"scheduler latency", func() float64 {
+			procs := runtime.GOMAXPROCS(0)
+			return schedulerLatencyTest([]int{0, procs, 4 * procs}, 1000*scaleFactor, time.Millisecond, time.Duration(scaleFactor)*time.Second)
+		}},
+		{"spinlock", func() float64 {
+			procs := runtime.GOMAXPROCS(0)
+			return spinlockTest([]int{1, max(procs/2, 1), procs, 2 * procs, 8 * procs}, 200000*scaleFactor)
+		}},
+		{"timeouts", func() float64 { return timeoutTest(8, 50000*scaleFactor, time.Second) }},
+		{"cache", func() float64 { return cacheTest(max(8, runtime.NumCPU()), 200000*scaleFactor, 100000, 10000) }},
+		{"batched channel", func() float64 { return batchedChannelTest(4, 250000*scaleFactor, []int{1, 8, 64, 512}) }},
+		{"mixed workload", func() float64 { return mixedWorkloadTest(4, 300*scaleFactor) }},
+		{"os threads", func() float64 {
+			cpus := runtime.NumCPU()
+			return osThreadTest([]int{cpus, 4 * cpus, 32 * cpus}, 400000*scaleFactor, 4, 20000*scaleFactor)
+		}},
+	}
+
+	totalTime := runTests(tests, *leakStacks)
+	if *extended {
+		runTests(extendedTests, *leakStacks)
+		// and -scaling reruns them along with the rest
+		tests = append(tests, extendedTests...)
+	}
+
+	// the scaling reruns are diagnostics only, the printed total stays the run above
+	if *scaling {
+		scalingReport(tests, scalingProcs(*scalingMax))
+	}
+
+	fmt.Printf("%.3f\n", totalTime)
+}
@@ -225,8 +225,26 @@ fi
 if [ $? -ne 0 ]; then echo "C++ compilation failed. Stopping."; exit 1; fi
 
 echo "Compiling Go code..."
-go build -ldflags="-s -w" -gcflags="-B" -o "concurrency_go${EXE_EXT}" concurrency.go
-if [ $? -ne 0 ]; then echo "Go compilation failed. Stopping."; exit 1; fi
+# go: hits the mock server like the other languages, without -target-url it starts its own
+# builds as a module so tagged files get picked up, tests needing golang.org/x
+# packages are opt-in build tags
+#   x/sync: errgroup semaphore
+#   x/time: rate
+# they only extend go-only tests, which run with -extended and stay out of the total
+# e.g. GO_BUILD_TAGS="errgroup" ./concurrency.sh
+# go won't build a directory that also holds .c files, so the go sources
+# build from a copy of their own
+rm -rf go_build && mkdir go_build && cp *.go go_build/
+cd go_build
+go mod init concurrency_bench > /dev/null 2>&1
+if [ -n "$GO_BUILD_TAGS" ]; then
+    echo "Building Go with tags: $GO_BUILD_TAGS"
+    go mod tidy > /dev/null 2>&1
+fi
+go build -tags "$GO_BUILD_TAGS" -ldflags="-s -w" -gcflags="-B" -o "../concurrency_go${EXE_EXT}" .
+go_status=$?
+cd ..
+if [ $go_status -ne 0 ]; then echo "Go compilation failed. Stopping."; exit 1; fi
 
 # julia doesn't need compilation, it's JIT compiled
 echo "Julia ready (JIT compiled at runtime)"
@@ -263,7 +281,7 @@ echo ""
 if [ "$IS_WINDOWS" = true ]; then
     C_CMD="./concurrency_c.exe ${SCALE_FACTOR}"
     CPP_CMD="./concurrency_cpp.exe ${SCALE_FACTOR}"
-    GO_CMD="./concurrency_go.exe ${SCALE_FACTOR}"
+    GO_CMD="./concurrency_go.exe -target-url http://127.0.0.1:8000/fast ${SCALE_FACTOR}"
     RUST_CMD="./concurrency_rust.exe ${SCALE_FACTOR}"
     NIM_CMD="./concurrency_nim.exe ${SCALE_FACTOR}"
     JAVA_CMD="java -server concurrency ${SCALE_FACTOR}"
@@ -271,7 +289,7 @@ if [ "$IS_WINDOWS" = true ]; then
 else
     C_CMD="./concurrency_c ${SCALE_FACTOR}"
     CPP_CMD="./concurrency_cpp ${SCALE_FACTOR}"
-    GO_CMD="./concurrency_go ${SCALE_FACTOR}"
+    GO_CMD="./concurrency_go -target-url http://127.0.0.1:8000/fast ${SCALE_FACTOR}"
     RUST_CMD="./concurrency_rust ${SCALE_FACTOR}"
     NIM_CMD="./concurrency_nim ${SCALE_FACTOR}"
     JAVA_CMD="java -server concurrency ${SCALE_FACTOR}"
@@ -333,5 +351,6 @@ fi
 echo "Cleaning up generated files..."
 rm -rf server configs target
 rm -f Cargo.toml Cargo.lock
+rm -rf go_build
 
 echo "All done! Thanks for running this comprehensive concurrency benchmark!"
\ No newline at end of file
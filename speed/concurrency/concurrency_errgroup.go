@@ -0,0 +1,18 @@
+//go:build errgroup
+
+package main
+
+import (
+	"context"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// x/sync's errgroup next to the built-in group
+func init() {
+	groupImpls = append(groupImpls, groupImpl{"x/sync errgroup", func(ctx context.Context, limit int) (taskGroup, context.Context) {
+		g, ctx := errgroup.WithContext(ctx)
+		g.SetLimit(limit)
+		return g, ctx
+	}})
+}
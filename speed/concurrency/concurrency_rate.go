@@ -0,0 +1,22 @@
+//go:build rate
+
+package main
+
+import (
+	"context"
+
+	"golang.org/x/time/rate"
+)
+
+// x/time's token bucket, refilled at perSecond and holding up to burst tokens
+func init() {
+	rateLimiterImpls = append(rateLimiterImpls, rateLimiterImpl{"x/time token bucket", func(perSecond float64, burst int) rateLimiter {
+		return tokenBucketLimiter{rate.NewLimiter(rate.Limit(perSecond), burst)}
+	}})
+}
+
+type tokenBucketLimiter struct {
+	limiter *rate.Limiter
+}
+
+func (l tokenBucketLimiter) wait(ctx context.Context) error { return l.limiter.Wait(ctx) }
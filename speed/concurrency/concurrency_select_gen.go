@@ -0,0 +1,56 @@
+//go:build ignore
+
+// generates concurrency_select.go, the fixed-arity selects used by the
+// fan in/out test, run with go generate
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+)
+
+func main() {
+	var b bytes.Buffer
+	b.WriteString("// Code generated by concurrency_select_gen.go; DO NOT EDIT.\n\npackage main\n")
+
+	var sizes []int
+	for n := 1; n <= 64; n *= 2 {
+		sizes = append(sizes, n)
+	}
+
+	for _, n := range sizes {
+		fmt.Fprintf(&b, "\nfunc selectRecv%d(c []chan int) (int, int, bool) {\n\tselect {\n", n)
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(&b, "\tcase v, ok := <-c[%d]:\n\t\treturn %d, v, ok\n", i, i)
+		}
+		b.WriteString("\t}\n}\n")
+
+		fmt.Fprintf(&b, "\nfunc selectSend%d(c []chan int, v int) {\n\tselect {\n", n)
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(&b, "\tcase c[%d] <- v:\n", i)
+		}
+		b.WriteString("\t}\n}\n")
+	}
+
+	b.WriteString("\n// selectRecvN and selectSendN hold a select with exactly n cases per n\n")
+	b.WriteString("var selectRecvN = map[int]func([]chan int) (int, int, bool){\n")
+	for _, n := range sizes {
+		fmt.Fprintf(&b, "\t%d: selectRecv%d,\n", n, n)
+	}
+	b.WriteString("}\n\nvar selectSendN = map[int]func([]chan int, int){\n")
+	for _, n := range sizes {
+		fmt.Fprintf(&b, "\t%d: selectSend%d,\n", n, n)
+	}
+	b.WriteString("}\n")
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("concurrency_select.go", src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,23 @@
+//go:build semaphore
+
+package main
+
+import (
+	"context"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// x/sync's weighted semaphore, every holder takes a weight of one
+func init() {
+	limiterImpls = append(limiterImpls, limiterImpl{"x/sync semaphore", func(limit int) limiter {
+		return weightedLimiter{semaphore.NewWeighted(int64(limit))}
+	}})
+}
+
+type weightedLimiter struct {
+	sem *semaphore.Weighted
+}
+
+func (l weightedLimiter) acquire() { l.sem.Acquire(context.Background(), 1) }
+func (l weightedLimiter) release() { l.sem.Release(1) }
@@ -0,0 +1,120 @@
+//go:build arrow
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func init() {
+	optionalTests = append(optionalTests, optionalTest{
+		name: "arrow",
+		run: func(workdir string, scaleFactor int) (float64, error) {
+			return arrowIPCTest(filepath.Join(workdir, "output.arrows"), 100000*scaleFactor, 64*1024)
+		},
+	})
+}
+
+// arrow ipc builds record batches from the product records, writes them as an
+// ipc stream and reads them back scanning the price and category columns
+func arrowIPCTest(filename string, numRecords int, batchSize int) (float64, error) {
+	records := generateProductRecords(numRecords)
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "product_name", Type: arrow.BinaryTypes.String},
+		{Name: "price", Type: arrow.PrimitiveTypes.Float64},
+		{Name: "category", Type: arrow.BinaryTypes.String},
+	}, nil)
+	pool := memory.NewGoAllocator()
+
+	start := time.Now()
+	file, err := os.Create(filename)
+	if err != nil {
+		return 0.0, fmt.Errorf("could not create file -> %s: %w", filename, err)
+	}
+	writer := ipc.NewWriter(file, ipc.WithSchema(schema), ipc.WithAllocator(pool))
+	builder := array.NewRecordBuilder(pool, schema)
+	ids := builder.Field(0).(*array.Int64Builder)
+	names := builder.Field(1).(*array.StringBuilder)
+	prices := builder.Field(2).(*array.Float64Builder)
+	categories := builder.Field(3).(*array.StringBuilder)
+
+	batches := 0
+	for offset := 0; offset < numRecords; offset += batchSize {
+		end := min(offset+batchSize, numRecords)
+		for _, record := range records[offset:end] {
+			ids.Append(record.ID)
+			names.Append(record.Name)
+			prices.Append(record.Price)
+			categories.Append(record.Category)
+		}
+		batch := builder.NewRecord()
+		err := writer.Write(batch)
+		batch.Release()
+		if err != nil {
+			builder.Release()
+			file.Close()
+			os.Remove(filename)
+			return 0.0, fmt.Errorf("writing arrow batch -> %w", err)
+		}
+		batches++
+	}
+	builder.Release()
+	writer.Close()
+	file.Close()
+	end := time.Now()
+	writeTime := float64(end.Sub(start).Microseconds()) / 1000.0
+	defer os.Remove(filename)
+
+	start = time.Now()
+	file, err = os.Open(filename)
+	if err != nil {
+		return 0.0, fmt.Errorf("could not open file -> %s: %w", filename, err)
+	}
+	defer file.Close()
+	reader, err := ipc.NewReader(file, ipc.WithAllocator(pool))
+	if err != nil {
+		return 0.0, fmt.Errorf("could not open arrow stream -> %w", err)
+	}
+	rowsRead := 0
+	readBatches := 0
+	priceSum := 0.0
+	matches := 0
+	for reader.Next() {
+		batch := reader.Record()
+		for _, price := range batch.Column(2).(*array.Float64).Float64Values() {
+			priceSum += price
+		}
+		categoryColumn := batch.Column(3).(*array.String)
+		for i := 0; i < categoryColumn.Len(); i++ {
+			if categoryColumn.Value(i) == "Category-3" {
+				matches++
+			}
+		}
+		rowsRead += int(batch.NumRows())
+		readBatches++
+	}
+	readErr := reader.Err()
+	reader.Release()
+	if readErr != nil {
+		return 0.0, fmt.Errorf("reading arrow stream -> %w", readErr)
+	}
+	end = time.Now()
+	readTime := float64(end.Sub(start).Microseconds()) / 1000.0
+	_ = priceSum + float64(matches)
+
+	streamSize := fileSize(file)
+	// ops are record batches
+	addResult("arrow write", writeTime, streamSize, int64(numRecords), int64(batches))
+	addResult("arrow read", readTime, streamSize, int64(rowsRead), int64(readBatches))
+	return writeTime + readTime, nil
+}
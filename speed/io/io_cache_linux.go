@@ -0,0 +1,33 @@
+//go:build linux && (amd64 || arm64)
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+const posixFadvDontNeed = 4
+
+func init() {
+	evictFromPageCache = fadviseDontNeed
+}
+
+// fadviseDontNeed calls posix_fadvise(POSIX_FADV_DONTNEED) over the whole file,
+// dirty pages can't be dropped so the file is synced first
+func fadviseDontNeed(filename string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	_, _, errno := syscall.Syscall6(syscall.SYS_FADVISE64, file.Fd(), 0, 0, posixFadvDontNeed, 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
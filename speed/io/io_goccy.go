@@ -0,0 +1,19 @@
+//go:build goccy
+
+package main
+
+import (
+	"io"
+
+	gojson "github.com/goccy/go-json"
+)
+
+// goccy/go-json, a drop-in replacement for encoding/json
+func init() {
+	jsonCodecs = append(jsonCodecs, jsonCodec{
+		name:       "goccy/go-json",
+		unmarshal:  gojson.Unmarshal,
+		newDecoder: func(r io.Reader) jsonDecoder { return gojson.NewDecoder(r) },
+		newEncoder: func(w io.Writer) jsonEncoder { return gojson.NewEncoder(w) },
+	})
+}
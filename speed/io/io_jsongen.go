@@ -0,0 +1,150 @@
+//go:build jsongen
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// hand-written encoder in the style easyjson generates: no reflection, just
+// appending bytes for the concrete jsonDocument type
+// decoding targets map[string]any, where generated code has nothing to offer,
+// so it goes through encoding/json and those numbers match the stdlib ones
+func init() {
+	jsonCodecs = append(jsonCodecs, jsonCodec{
+		name:       "generated",
+		unmarshal:  json.Unmarshal,
+		newDecoder: func(r io.Reader) jsonDecoder { return json.NewDecoder(r) },
+		newEncoder: func(w io.Writer) jsonEncoder { return &generatedEncoder{w: w} },
+	})
+}
+
+type generatedEncoder struct {
+	w   io.Writer
+	buf []byte
+}
+
+func (e *generatedEncoder) Encode(v any) error {
+	doc, ok := v.(jsonDocument)
+	if !ok {
+		return json.NewEncoder(e.w).Encode(v)
+	}
+
+	buf := e.buf[:0]
+	buf = append(buf, `{"metadata":{`...)
+	keys := make([]string, 0, len(doc.Metadata))
+	for k := range doc.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = appendGeneratedString(buf, k)
+		buf = append(buf, ':')
+		buf = strconv.AppendInt(buf, int64(doc.Metadata[k]), 10)
+	}
+	buf = append(buf, `},"items":[`...)
+
+	for i, item := range doc.Items {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, `{"id":`...)
+		buf = strconv.AppendInt(buf, int64(item.ID), 10)
+		buf = append(buf, `,"name":`...)
+		buf = appendGeneratedString(buf, item.Name)
+		buf = append(buf, `,"attributes":`...)
+		var err error
+		if buf, err = appendGeneratedAttributes(buf, item.Attributes); err != nil {
+			return err
+		}
+		buf = append(buf, '}')
+
+		// hand full chunks to the writer so the buffer stays bounded
+		if len(buf) > 256*1024 {
+			if _, err := e.w.Write(buf); err != nil {
+				return err
+			}
+			buf = buf[:0]
+		}
+	}
+	buf = append(buf, "]}\n"...)
+
+	_, err := e.w.Write(buf)
+	e.buf = buf[:0]
+	return err
+}
+
+// appendGeneratedAttributes writes the attribute map with sorted keys like
+// encoding/json does, falling back to reflection only for unexpected types
+func appendGeneratedAttributes(buf []byte, attrs map[string]any) ([]byte, error) {
+	if attrs == nil {
+		return append(buf, "null"...), nil
+	}
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	buf = append(buf, '{')
+	for i, k := range keys {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = appendGeneratedString(buf, k)
+		buf = append(buf, ':')
+		switch value := attrs[k].(type) {
+		case bool:
+			buf = strconv.AppendBool(buf, value)
+		case int:
+			buf = strconv.AppendInt(buf, int64(value), 10)
+		case float64:
+			buf = appendGeneratedFloat(buf, value)
+		case string:
+			buf = appendGeneratedString(buf, value)
+		default:
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return buf, err
+			}
+			buf = append(buf, encoded...)
+		}
+	}
+	return append(buf, '}'), nil
+}
+
+// appendGeneratedFloat follows encoding/json's choice between plain and exponent form
+func appendGeneratedFloat(buf []byte, f float64) []byte {
+	abs := math.Abs(f)
+	format := byte('f')
+	if abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+	return strconv.AppendFloat(buf, f, format, -1, 64)
+}
+
+// appendGeneratedString quotes s with json escaping (strconv.Quote uses go
+// escapes like \x00 that aren't valid json)
+func appendGeneratedString(buf []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	buf = append(buf, '"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			buf = append(buf, '\\', c)
+		case c < 0x20:
+			buf = append(buf, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xF])
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return append(buf, '"')
+}
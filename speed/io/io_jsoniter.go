@@ -0,0 +1,20 @@
+//go:build jsoniter
+
+package main
+
+import (
+	"io"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// json-iterator in its encoding/json compatible configuration
+func init() {
+	api := jsoniter.ConfigCompatibleWithStandardLibrary
+	jsonCodecs = append(jsonCodecs, jsonCodec{
+		name:       "json-iterator",
+		unmarshal:  api.Unmarshal,
+		newDecoder: func(r io.Reader) jsonDecoder { return api.NewDecoder(r) },
+		newEncoder: func(w io.Writer) jsonEncoder { return api.NewEncoder(w) },
+	})
+}
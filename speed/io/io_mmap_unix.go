@@ -0,0 +1,22 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+func init() {
+	mapFile = mmapReadOnly
+}
+
+// mmapReadOnly maps the file shared and read-only, the caller must not touch
+// the slice after calling the returned unmap
+func mmapReadOnly(file *os.File, size int64) ([]byte, func() error, error) {
+	data, err := syscall.Mmap(int(file.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return syscall.Munmap(data) }, nil
+}
@@ -0,0 +1,109 @@
+//go:build parquet
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func init() {
+	optionalTests = append(optionalTests, optionalTest{
+		name: "parquet",
+		run: func(workdir string, scaleFactor int) (float64, error) {
+			return parquetReadWriteTest(filepath.Join(workdir, "output.parquet"), 100000*scaleFactor)
+		},
+	})
+}
+
+// parquet write encodes the product records into row groups, parquet read
+// decodes them back in batches, sizes are compared to csv and json lines
+func parquetReadWriteTest(filename string, numRecords int) (float64, error) {
+	records := generateProductRecords(numRecords)
+
+	start := time.Now()
+	file, err := os.Create(filename)
+	if err != nil {
+		return 0.0, fmt.Errorf("could not create file -> %s: %w", filename, err)
+	}
+	writer := parquet.NewGenericWriter[productRecord](file, parquet.MaxRowsPerRowGroup(64*1024))
+	defer os.Remove(filename)
+	if _, err := writer.Write(records); err != nil {
+		file.Close()
+		return 0.0, fmt.Errorf("writing parquet -> %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		file.Close()
+		return 0.0, fmt.Errorf("closing parquet writer -> %w", err)
+	}
+	file.Close()
+	end := time.Now()
+	writeTime := float64(end.Sub(start).Microseconds()) / 1000.0
+
+	start = time.Now()
+	file, err = os.Open(filename)
+	if err != nil {
+		return 0.0, fmt.Errorf("could not open file -> %s: %w", filename, err)
+	}
+	defer file.Close()
+	reader := parquet.NewGenericReader[productRecord](file)
+	batch := make([]productRecord, 4096)
+	rowsRead := 0
+	priceSum := 0.0
+	for {
+		n, err := reader.Read(batch)
+		for _, record := range batch[:n] {
+			priceSum += record.Price
+		}
+		rowsRead += n
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			reader.Close()
+			return 0.0, fmt.Errorf("reading parquet -> %w", err)
+		}
+	}
+	reader.Close()
+	end = time.Now()
+	readTime := float64(end.Sub(start).Microseconds()) / 1000.0
+	_ = priceSum
+
+	info, err := os.Stat(filename)
+	parquetSize := int64(0)
+	if err == nil {
+		parquetSize = info.Size()
+	}
+
+	// size the same records as csv and json lines without touching the disk
+	csvSize := &countingWriter{}
+	csvWriter := csv.NewWriter(csvSize)
+	csvWriter.Write([]string{"id", "product_name", "price", "category"})
+	for _, record := range records {
+		csvWriter.Write([]string{
+			strconv.FormatInt(record.ID, 10),
+			record.Name,
+			strconv.FormatFloat(record.Price, 'f', 2, 64),
+			record.Category,
+		})
+	}
+	csvWriter.Flush()
+	jsonSize := &countingWriter{}
+	encoder := json.NewEncoder(jsonSize)
+	for _, record := range records {
+		encoder.Encode(record)
+	}
+
+	addResult("parquet write", writeTime, parquetSize, int64(numRecords), 0)
+	addResult("parquet read", readTime, parquetSize, int64(rowsRead), 0)
+	report("parquet size %d bytes  csv %d bytes  jsonl %d bytes", parquetSize, csvSize.n, jsonSize.n)
+	return writeTime + readTime, nil
+}
@@ -0,0 +1,110 @@
+//go:build s3
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// opt-in twice: the s3 build tag pulls in minio-go, and the test only runs
+// when S3_ENDPOINT points at an s3-compatible server (e.g. a local minio)
+func init() {
+	optionalTests = append(optionalTests, optionalTest{
+		name: "s3",
+		run: func(workdir string, scaleFactor int) (float64, error) {
+			endpoint := os.Getenv("S3_ENDPOINT")
+			if endpoint == "" {
+				log.Print("S3_ENDPOINT not set, skipping s3 test")
+				return 0.0, nil
+			}
+			return s3TransferTest(endpoint, os.Getenv("S3_BUCKET"), filepath.Join(workdir, "data.bin"))
+		},
+	})
+}
+
+// s3 transfer uploads the binary file as a single PUT and as a multipart
+// upload, then downloads it back, against an s3-compatible endpoint
+func s3TransferTest(endpoint string, bucket string, filename string) (float64, error) {
+	if bucket == "" {
+		bucket = "io-bench"
+	}
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(os.Getenv("S3_ACCESS_KEY"), os.Getenv("S3_SECRET_KEY"), ""),
+		Secure: os.Getenv("S3_SECURE") == "1",
+	})
+	if err != nil {
+		return 0.0, fmt.Errorf("could not create s3 client -> %w", err)
+	}
+
+	ctx := context.Background()
+	exists, err := client.BucketExists(ctx, bucket)
+	if err != nil {
+		return 0.0, fmt.Errorf("could not reach s3 endpoint -> %w", err)
+	}
+	if !exists {
+		if err := client.MakeBucket(ctx, bucket, minio.MakeBucketOptions{}); err != nil {
+			return 0.0, fmt.Errorf("could not create bucket -> %w", err)
+		}
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		return 0.0, fmt.Errorf("could not stat file -> %s: %w", filename, err)
+	}
+	size := info.Size()
+
+	uploads := []struct {
+		name string
+		key  string
+		opts minio.PutObjectOptions
+	}{
+		{"s3 upload single", "single.bin", minio.PutObjectOptions{DisableMultipart: true}},
+		// 5MB is the smallest part size s3 accepts
+		{"s3 upload multipart", "multipart.bin", minio.PutObjectOptions{PartSize: 5 * 1024 * 1024, NumThreads: 4}},
+	}
+
+	totalTime := 0.0
+	for _, upload := range uploads {
+		file, err := os.Open(filename)
+		if err != nil {
+			return 0.0, fmt.Errorf("could not open file -> %s: %w", filename, err)
+		}
+		start := time.Now()
+		_, err = client.PutObject(ctx, bucket, upload.key, file, size, upload.opts)
+		end := time.Now()
+		file.Close()
+		if err != nil {
+			return totalTime, fmt.Errorf("%s failed -> %w", upload.name, err)
+		}
+		elapsed := float64(end.Sub(start).Microseconds()) / 1000.0
+		totalTime += addResult(upload.name, elapsed, size, 0, 1)
+	}
+
+	start := time.Now()
+	object, err := client.GetObject(ctx, bucket, "multipart.bin", minio.GetObjectOptions{})
+	if err != nil {
+		return totalTime, fmt.Errorf("s3 download failed -> %w", err)
+	}
+	downloaded, err := io.Copy(io.Discard, object)
+	object.Close()
+	end := time.Now()
+	if err != nil {
+		return totalTime, fmt.Errorf("s3 download failed -> %w", err)
+	}
+	elapsed := float64(end.Sub(start).Microseconds()) / 1000.0
+	totalTime += addResult("s3 download", elapsed, downloaded, 0, 1)
+
+	for _, upload := range uploads {
+		client.RemoveObject(ctx, bucket, upload.key, minio.RemoveObjectOptions{})
+	}
+	return totalTime, nil
+}
@@ -0,0 +1,116 @@
+//go:build sqlite
+
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+func init() {
+	optionalTests = append(optionalTests, optionalTest{
+		name: "sqlite",
+		run: func(workdir string, scaleFactor int) (float64, error) {
+			return sqliteWorkloadTest(filepath.Join(workdir, "output.db"), 100000*scaleFactor, 200)
+		},
+	})
+}
+
+// sqlite workload bulk-inserts the product records into an on-disk database,
+// then runs point queries through an index and aggregate queries that scan the table
+func sqliteWorkloadTest(filename string, numRecords int, numQueries int) (float64, error) {
+	os.Remove(filename)
+	defer os.Remove(filename)
+
+	// pure go driver, no cgo needed
+	db, err := sql.Open("sqlite", filename)
+	if err != nil {
+		return 0.0, fmt.Errorf("could not open database -> %s: %w", filename, err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(`CREATE TABLE products (
+		id INTEGER PRIMARY KEY,
+		product_name TEXT NOT NULL,
+		price REAL NOT NULL,
+		category TEXT NOT NULL
+	)`); err != nil {
+		return 0.0, fmt.Errorf("could not create table -> %w", err)
+	}
+
+	records := generateProductRecords(numRecords)
+
+	// bulk insert, one transaction and one prepared statement
+	start := time.Now()
+	tx, err := db.Begin()
+	if err != nil {
+		return 0.0, fmt.Errorf("could not begin transaction -> %w", err)
+	}
+	stmt, err := tx.Prepare("INSERT INTO products (id, product_name, price, category) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		tx.Rollback()
+		return 0.0, fmt.Errorf("could not prepare insert -> %w", err)
+	}
+	for _, record := range records {
+		if _, err := stmt.Exec(record.ID, record.Name, record.Price, record.Category); err != nil {
+			stmt.Close()
+			tx.Rollback()
+			return 0.0, fmt.Errorf("insert failed -> %w", err)
+		}
+	}
+	stmt.Close()
+	if err := tx.Commit(); err != nil {
+		return 0.0, fmt.Errorf("commit failed -> %w", err)
+	}
+	if _, err := db.Exec("CREATE INDEX idx_products_category ON products (category)"); err != nil {
+		return 0.0, fmt.Errorf("could not create index -> %w", err)
+	}
+	end := time.Now()
+	insertTime := float64(end.Sub(start).Microseconds()) / 1000.0
+
+	// indexed lookups by primary key and by the category index
+	start = time.Now()
+	var name string
+	var count int
+	for i := 0; i < numQueries; i++ {
+		id := (i * 7919) % numRecords
+		if err := db.QueryRow("SELECT product_name FROM products WHERE id = ?", id).Scan(&name); err != nil {
+			return 0.0, fmt.Errorf("point query failed -> %w", err)
+		}
+		if err := db.QueryRow("SELECT COUNT(*) FROM products WHERE category = ?", "Category-3").Scan(&count); err != nil {
+			return 0.0, fmt.Errorf("index query failed -> %w", err)
+		}
+	}
+	end = time.Now()
+	indexedTime := float64(end.Sub(start).Microseconds()) / 1000.0
+
+	// full scans, price has no index so every row gets read
+	start = time.Now()
+	var total float64
+	scans := max(1, numQueries/20)
+	for i := 0; i < scans; i++ {
+		if err := db.QueryRow("SELECT COALESCE(SUM(price), 0) FROM products WHERE price > ?", float64(i)).Scan(&total); err != nil {
+			return 0.0, fmt.Errorf("scan query failed -> %w", err)
+		}
+	}
+	end = time.Now()
+	scanTime := float64(end.Sub(start).Microseconds()) / 1000.0
+	_ = len(name) + count
+	_ = total
+
+	dbSize := int64(0)
+	if info, err := os.Stat(filename); err == nil {
+		dbSize = info.Size()
+	}
+	// ops are sql queries
+	addResult("sqlite insert", insertTime, dbSize, int64(numRecords), 0)
+	addResult("sqlite indexed query", indexedTime, 0, 0, int64(numQueries*2))
+	addResult("sqlite scan query", scanTime, 0, int64(numRecords*scans), int64(scans))
+	return insertTime + indexedTime + scanTime, nil
+}
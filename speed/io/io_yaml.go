@@ -0,0 +1,102 @@
+//go:build yaml
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	optionalTests = append(optionalTests, optionalTest{
+		name: "yaml",
+		run: func(workdir string, scaleFactor int) (float64, error) {
+			return yamlReadWriteTest(filepath.Join(workdir, "data.yaml"), filepath.Join(workdir, "output.yaml"), 2000*scaleFactor)
+		},
+	})
+}
+
+// generateYAMLFile writes a config-like document: a defaults block reused
+// through anchors and merge keys, then nested service maps with lists
+func generateYAMLFile(filename string, numServices int) error {
+	var b strings.Builder
+	b.WriteString("defaults: &defaults\n")
+	b.WriteString("  retries: 3\n")
+	b.WriteString("  timeout_ms: 5000\n")
+	b.WriteString("  tags: [benchmark, generated]\n")
+	b.WriteString("services:\n")
+	for i := 0; i < numServices; i++ {
+		fmt.Fprintf(&b, "  service-%d:\n", i)
+		b.WriteString("    <<: *defaults\n")
+		fmt.Fprintf(&b, "    port: %d\n", 8000+i%1000)
+		fmt.Fprintf(&b, "    replicas: %d\n", 1+i%5)
+		b.WriteString("    endpoints:\n")
+		for j := 0; j < 3; j++ {
+			fmt.Fprintf(&b, "      - path: /api/v%d/resource-%d\n", j+1, i)
+			fmt.Fprintf(&b, "        weight: %.2f\n", float64(j+1)*0.25)
+		}
+		b.WriteString("    limits:\n")
+		fmt.Fprintf(&b, "      cpu: %dm\n", 100*(1+i%8))
+		fmt.Fprintf(&b, "      memory: %dMi\n", 128*(1+i%4))
+	}
+	return os.WriteFile(filename, []byte(b.String()), 0644)
+}
+
+// yaml read resolves anchors/merges into generic maps, yaml write dumps them back
+func yamlReadWriteTest(readFile string, writeFile string, numServices int) (float64, error) {
+	if err := generateYAMLFile(readFile, numServices); err != nil {
+		return 0.0, fmt.Errorf("could not generate yaml file -> %s: %w", readFile, err)
+	}
+	defer os.Remove(readFile)
+
+	start := time.Now()
+	content, err := os.ReadFile(readFile)
+	if err != nil {
+		return 0.0, fmt.Errorf("could not read file -> %s: %w", readFile, err)
+	}
+	var doc map[string]any
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return 0.0, fmt.Errorf("could not parse yaml -> %w", err)
+	}
+	replicas := 0
+	if services, ok := doc["services"].(map[string]any); ok {
+		for _, service := range services {
+			if fields, ok := service.(map[string]any); ok {
+				if n, ok := fields["replicas"].(int); ok {
+					replicas += n
+				}
+			}
+		}
+	}
+	end := time.Now()
+	readTime := float64(end.Sub(start).Microseconds()) / 1000.0
+	_ = replicas
+
+	start = time.Now()
+	file, err := os.Create(writeFile)
+	if err != nil {
+		return 0.0, fmt.Errorf("could not create file -> %s: %w", writeFile, err)
+	}
+	written := &countingWriter{w: file}
+	encoder := yaml.NewEncoder(written)
+	encoder.SetIndent(2)
+	err = encoder.Encode(doc)
+	encoder.Close()
+	file.Close()
+	if err != nil {
+		os.Remove(writeFile)
+		return 0.0, fmt.Errorf("could not write yaml -> %w", err)
+	}
+	end = time.Now()
+	writeTime := float64(end.Sub(start).Microseconds()) / 1000.0
+	os.Remove(writeFile)
+
+	addResult("yaml read", readTime, int64(len(content)), int64(numServices), 0)
+	addResult("yaml write", writeTime, written.n, int64(numServices), 0)
+	return readTime + writeTime, nil
+}
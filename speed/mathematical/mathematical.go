@@ -1,42 +1,32 @@
 package main
 
 import (
+	cryptorand "crypto/rand"
+	"encoding/binary"
+	"flag"
 	"fmt"
 	"math"
+	"math/big"
+	"math/bits"
 	"math/cmplx"
 	"math/rand"
+	randv2 "math/rand/v2"
 	"os"
+	"runtime"
 	"sort"
 	"strconv"
+	"sync"
 	"time"
 )
 
-func matrixOperations(size int) float64 {
-	a := make([][]float64, size)
-	b := make([][]float64, size)
-	c := make([][]float64, size)
-	temp := make([][]float64, size)
-	
-	for i := range a {
-		a[i] = make([]float64, size)
-		b[i] = make([]float64, size)
-		c[i] = make([]float64, size)
-		temp[i] = make([]float64, size)
-	}
-	
-	rand.Seed(42)
-	for i := 0; i < size; i++ {
-		for j := 0; j < size; j++ {
-			a[i][j] = rand.Float64()*9 + 1
-			b[i][j] = rand.Float64()*9 + 1
-		}
-	}
-	
-	start := time.Now()
-	
-	// blocked matrix multiplication
-	block := 32
-	for ii := 0; ii < size; ii += block {
+// float lets the matrix kernels run in single and double precision
+type float interface {
+	~float32 | ~float64
+}
+
+// blockedMultiply adds a*b into c for the rows in block rows [rowStart, rowEnd)
+func blockedMultiply[T float](a, b, c [][]T, size, block, rowStart, rowEnd int) {
+	for ii := rowStart; ii < rowEnd; ii += block {
 		for jj := 0; jj < size; jj += block {
 			for kk := 0; kk < size; kk += block {
 				iMax := min(ii+block, size)
@@ -52,33 +42,617 @@ func matrixOperations(size int) float64 {
 			}
 		}
 	}
-	
+}
+
+// parallelBlockedMultiply hands block rows out to workers goroutines, each
+// block row of c is only ever written by the worker that took it
+func parallelBlockedMultiply[T float](a, b, c [][]T, size, block, workers int) {
+	rows := make(chan int, (size+block-1)/block)
+	for ii := 0; ii < size; ii += block {
+		rows <- ii
+	}
+	close(rows)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ii := range rows {
+				blockedMultiply(a, b, c, size, block, ii, min(ii+block, size))
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func newMatrix[T float](size int) [][]T {
+	m := make([][]T, size)
+	for i := range m {
+		m[i] = make([]T, size)
+	}
+	return m
+}
+
+// transposeAndScale leaves c transposed in temp and sets c to temp + a*scalar
+func transposeAndScale[T float](a, c, temp [][]T, size int, scalar T) {
 	// matrix transpose
 	for i := 0; i < size; i++ {
 		for j := 0; j < size; j++ {
 			temp[j][i] = c[i][j]
 		}
 	}
-	
+
 	// matrix operations
-	scalar := 1.5
 	for i := 0; i < size; i++ {
 		for j := 0; j < size; j++ {
 			c[i][j] = temp[i][j] + a[i][j]*scalar
 		}
 	}
-	
+}
+
+// toFloat32 copies a matrix down to single precision
+func toFloat32(m [][]float64) [][]float32 {
+	out := newMatrix[float32](len(m))
+	for i, row := range m {
+		for j, v := range row {
+			out[i][j] = float32(v)
+		}
+	}
+	return out
+}
+
+// autoTuneBlock times the blocked multiply at each candidate block size on
+// size x size matrices, best of three runs each, and returns the fastest
+func autoTuneBlock(size int, candidates []int) int {
+	a, b, c := newMatrix[float64](size), newMatrix[float64](size), newMatrix[float64](size)
+	rand.Seed(7)
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
+			a[i][j] = rand.Float64()*9 + 1
+			b[i][j] = rand.Float64()*9 + 1
+		}
+	}
+
+	best, bestTime := candidates[0], time.Duration(math.MaxInt64)
+	fmt.Fprintf(os.Stderr, "block auto-tune at %dx%d:", size, size)
+	for _, block := range candidates {
+		fastest := time.Duration(math.MaxInt64)
+		for run := 0; run < 3; run++ {
+			start := time.Now()
+			blockedMultiply(a, b, c, size, block, 0, size)
+			if elapsed := time.Since(start); elapsed < fastest {
+				fastest = elapsed
+			}
+		}
+		fmt.Fprintf(os.Stderr, " %d=%.3fms", block, float64(fastest.Nanoseconds())/1000000.0)
+		if fastest < bestTime {
+			best, bestTime = block, fastest
+		}
+	}
+	fmt.Fprintf(os.Stderr, ", using %d\n", best)
+	return best
+}
+
+// matrix operations times the serial float64 kernels like the other
+// languages do, then repeats them in float32 and the multiply on workers
+// goroutines, reporting those without adding them to the total
+func matrixOperations(size int, block int, workers int) float64 {
+	a := newMatrix[float64](size)
+	b := newMatrix[float64](size)
+	c := newMatrix[float64](size)
+	temp := newMatrix[float64](size)
+
+	rand.Seed(42)
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
+			a[i][j] = rand.Float64()*9 + 1
+			b[i][j] = rand.Float64()*9 + 1
+		}
+	}
+
+	start := time.Now()
+
+	// blocked matrix multiplication
+	blockedMultiply(a, b, c, size, block, 0, size)
+	multiplyDuration := time.Since(start)
+
+	transposeAndScale(a, c, temp, size, 1.5)
+
 	duration := time.Since(start)
-	
+
 	sum := 0.0
 	for i := 0; i < size; i++ {
 		sum += c[i][i]
 	}
 	_ = sum
-	
+
+	a32, b32 := toFloat32(a), toFloat32(b)
+	c32, temp32 := newMatrix[float32](size), newMatrix[float32](size)
+	start32 := time.Now()
+	blockedMultiply(a32, b32, c32, size, block, 0, size)
+	multiply32 := time.Since(start32)
+	transposeAndScale(a32, c32, temp32, size, 1.5)
+	duration32 := time.Since(start32)
+
+	// temp holds the float64 product transposed, float32 keeps about 7 digits
+	maxRelErr := 0.0
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
+			maxRelErr = math.Max(maxRelErr, math.Abs(float64(temp32[j][i])-temp[j][i])/temp[j][i])
+		}
+	}
+	flops := 2 * float64(size) * float64(size) * float64(size)
+	fmt.Fprintf(os.Stderr, "matrix %dx%d float64: block %d, multiply %.3f ms (%.2f GFLOP/s), all kernels %.3f ms\n",
+		size, size, block, float64(multiplyDuration.Nanoseconds())/1000000.0, flops/float64(multiplyDuration.Nanoseconds()),
+		float64(duration.Nanoseconds())/1000000.0)
+	fmt.Fprintf(os.Stderr, "matrix %dx%d float32: multiply %.3f ms (%.2f GFLOP/s), all kernels %.3f ms, max rel error vs float64 %.1e\n",
+		size, size, float64(multiply32.Nanoseconds())/1000000.0, flops/float64(multiply32.Nanoseconds()),
+		float64(duration32.Nanoseconds())/1000000.0, maxRelErr)
+
+	parallel := newMatrix[float64](size)
+	parallelStart := time.Now()
+	parallelBlockedMultiply(a, b, parallel, size, block, workers)
+	parallelDuration := time.Since(parallelStart)
+
+	// temp still holds the serial product, transposed
+check:
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
+			if parallel[i][j] != temp[j][i] {
+				fmt.Fprintf(os.Stderr, "parallel multiply differs at %d,%d\n", i, j)
+				break check
+			}
+		}
+	}
+	speedup := float64(multiplyDuration) / float64(parallelDuration)
+	fmt.Fprintf(os.Stderr, "matmul %dx%d: serial %.3f ms, parallel %.3f ms on %d workers, speedup %.2fx, efficiency %.0f%%\n",
+		size, size, float64(multiplyDuration.Nanoseconds())/1000000.0, float64(parallelDuration.Nanoseconds())/1000000.0,
+		workers, speedup, 100*speedup/float64(workers))
+
 	return float64(duration.Nanoseconds()) / 1000000.0
 }
 
+// quadrant views rows [r, r+h) and columns [c, c+h) of m without copying
+func quadrant(m [][]float64, r, c, h int) [][]float64 {
+	q := make([][]float64, h)
+	for i := range q {
+		q[i] = m[r+i][c : c+h]
+	}
+	return q
+}
+
+// addInto sets dst = a + sign*b
+func addInto(dst, a, b [][]float64, sign float64) [][]float64 {
+	for i := range dst {
+		for j := range dst[i] {
+			dst[i][j] = a[i][j] + sign*b[i][j]
+		}
+	}
+	return dst
+}
+
+// strassenCutoff is where strassen stops recursing and multiplies naively,
+// below it the extra additions cost more than the saved multiply
+const strassenCutoff = 64
+
+// strassen multiplies square power of two matrices into c with seven half
+// size products per level instead of eight
+func strassen(a, b, c [][]float64) {
+	n := len(a)
+	if n <= strassenCutoff {
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				sum := 0.0
+				for k := 0; k < n; k++ {
+					sum += a[i][k] * b[k][j]
+				}
+				c[i][j] = sum
+			}
+		}
+		return
+	}
+
+	h := n / 2
+	a11, a12, a21, a22 := quadrant(a, 0, 0, h), quadrant(a, 0, h, h), quadrant(a, h, 0, h), quadrant(a, h, h, h)
+	b11, b12, b21, b22 := quadrant(b, 0, 0, h), quadrant(b, 0, h, h), quadrant(b, h, 0, h), quadrant(b, h, h, h)
+	s1, s2 := newMatrix[float64](h), newMatrix[float64](h)
+	m := make([][][]float64, 7)
+	for i := range m {
+		m[i] = newMatrix[float64](h)
+	}
+
+	strassen(addInto(s1, a11, a22, 1), addInto(s2, b11, b22, 1), m[0])
+	strassen(addInto(s1, a21, a22, 1), b11, m[1])
+	strassen(a11, addInto(s2, b12, b22, -1), m[2])
+	strassen(a22, addInto(s2, b21, b11, -1), m[3])
+	strassen(addInto(s1, a11, a12, 1), b22, m[4])
+	strassen(addInto(s1, a21, a11, -1), addInto(s2, b11, b12, 1), m[5])
+	strassen(addInto(s1, a12, a22, -1), addInto(s2, b21, b22, 1), m[6])
+
+	for i := 0; i < h; i++ {
+		for j := 0; j < h; j++ {
+			c[i][j] = m[0][i][j] + m[3][i][j] - m[4][i][j] + m[6][i][j]
+			c[i][j+h] = m[2][i][j] + m[4][i][j]
+			c[i+h][j] = m[1][i][j] + m[3][i][j]
+			c[i+h][j+h] = m[0][i][j] - m[1][i][j] + m[2][i][j] + m[5][i][j]
+		}
+	}
+}
+
+// strassen test multiplies two size x size matrices with the blocked
+// classical algorithm and with strassen, which pads them with zeros up to a
+// power of two, and checks the products agree within a relative tolerance
+func strassenTest(size int, block int) float64 {
+	a := newMatrix[float64](size)
+	b := newMatrix[float64](size)
+
+	rand.Seed(42)
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
+			a[i][j] = rand.Float64()*9 + 1
+			b[i][j] = rand.Float64()*9 + 1
+		}
+	}
+
+	start := time.Now()
+	classical := newMatrix[float64](size)
+	blockedMultiply(a, b, classical, size, block, 0, size)
+	classicalDuration := time.Since(start)
+
+	start = time.Now()
+	padded := nextPowerOfTwo(size)
+	pa, pb, pc := newMatrix[float64](padded), newMatrix[float64](padded), newMatrix[float64](padded)
+	for i := 0; i < size; i++ {
+		copy(pa[i], a[i])
+		copy(pb[i], b[i])
+	}
+	strassen(pa, pb, pc)
+	strassenDuration := time.Since(start)
+
+	maxRelErr := 0.0
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
+			maxRelErr = math.Max(maxRelErr, math.Abs(pc[i][j]-classical[i][j])/math.Abs(classical[i][j]))
+		}
+	}
+	if maxRelErr > 1e-9 {
+		fmt.Fprintf(os.Stderr, "strassen disagrees with the classical product, max rel error %.2e\n", maxRelErr)
+	}
+	fmt.Fprintf(os.Stderr, "strassen %dx%d (padded to %d, cutoff %d): blocked %.3f ms, strassen %.3f ms (%.2fx), max rel error %.1e\n",
+		size, size, padded, strassenCutoff, float64(classicalDuration.Nanoseconds())/1000000.0,
+		float64(strassenDuration.Nanoseconds())/1000000.0, float64(classicalDuration)/float64(strassenDuration), maxRelErr)
+
+	return float64((classicalDuration + strassenDuration).Nanoseconds()) / 1000000.0
+}
+
+// luDecompose factors a copy of a into PA = LU with partial pivoting, L below
+// the diagonal with an implied unit diagonal and U on and above it
+func luDecompose(a [][]float64) ([][]float64, []int, error) {
+	n := len(a)
+	lu := newMatrix[float64](n)
+	for i := range a {
+		copy(lu[i], a[i])
+	}
+	perm := make([]int, n)
+	for i := range perm {
+		perm[i] = i
+	}
+
+	for k := 0; k < n; k++ {
+		pivot := k
+		for i := k + 1; i < n; i++ {
+			if math.Abs(lu[i][k]) > math.Abs(lu[pivot][k]) {
+				pivot = i
+			}
+		}
+		if lu[pivot][k] == 0 {
+			return nil, nil, fmt.Errorf("matrix is singular at column %d", k)
+		}
+		lu[k], lu[pivot] = lu[pivot], lu[k]
+		perm[k], perm[pivot] = perm[pivot], perm[k]
+
+		for i := k + 1; i < n; i++ {
+			factor := lu[i][k] / lu[k][k]
+			lu[i][k] = factor
+			for j := k + 1; j < n; j++ {
+				lu[i][j] -= factor * lu[k][j]
+			}
+		}
+	}
+	return lu, perm, nil
+}
+
+// luSolve solves Ax = b from the factors, forward then back substitution
+func luSolve(lu [][]float64, perm []int, b []float64) []float64 {
+	n := len(lu)
+	x := make([]float64, n)
+	for i := 0; i < n; i++ {
+		sum := b[perm[i]]
+		for j := 0; j < i; j++ {
+			sum -= lu[i][j] * x[j]
+		}
+		x[i] = sum
+	}
+	for i := n - 1; i >= 0; i-- {
+		sum := x[i]
+		for j := i + 1; j < n; j++ {
+			sum -= lu[i][j] * x[j]
+		}
+		x[i] = sum / lu[i][i]
+	}
+	return x
+}
+
+// householderQR reduces a copy of a to upper triangular R, keeping the unit
+// householder vector of every column so Q^T can be applied later
+func householderQR(a [][]float64) ([][]float64, [][]float64) {
+	n := len(a)
+	r := newMatrix[float64](n)
+	for i := range a {
+		copy(r[i], a[i])
+	}
+	reflectors := make([][]float64, n)
+
+	for k := 0; k < n; k++ {
+		norm := 0.0
+		for i := k; i < n; i++ {
+			norm += r[i][k] * r[i][k]
+		}
+		norm = math.Sqrt(norm)
+
+		// reflect onto -sign(x0)*|x| so the subtraction doesn't cancel
+		v := make([]float64, n-k)
+		for i := k; i < n; i++ {
+			v[i-k] = r[i][k]
+		}
+		v[0] += math.Copysign(norm, v[0])
+		vNorm := 0.0
+		for _, vi := range v {
+			vNorm += vi * vi
+		}
+		vNorm = math.Sqrt(vNorm)
+		if vNorm == 0 {
+			continue
+		}
+		for i := range v {
+			v[i] /= vNorm
+		}
+		reflectors[k] = v
+
+		// rows outermost so both passes walk r row by row
+		dots := make([]float64, n-k)
+		for i := k; i < n; i++ {
+			for j := k; j < n; j++ {
+				dots[j-k] += v[i-k] * r[i][j]
+			}
+		}
+		for i := k; i < n; i++ {
+			for j := k; j < n; j++ {
+				r[i][j] -= 2 * v[i-k] * dots[j-k]
+			}
+		}
+	}
+	return r, reflectors
+}
+
+// qrSolve applies Q^T to b and back substitutes through R
+func qrSolve(r, reflectors [][]float64, b []float64) []float64 {
+	n := len(r)
+	y := make([]float64, n)
+	copy(y, b)
+	for k, v := range reflectors {
+		if v == nil {
+			continue
+		}
+		dot := 0.0
+		for i := k; i < n; i++ {
+			dot += v[i-k] * y[i]
+		}
+		for i := k; i < n; i++ {
+			y[i] -= 2 * v[i-k] * dot
+		}
+	}
+	for i := n - 1; i >= 0; i-- {
+		sum := y[i]
+		for j := i + 1; j < n; j++ {
+			sum -= r[i][j] * y[j]
+		}
+		y[i] = sum / r[i][i]
+	}
+	return y
+}
+
+// relativeResidual is ||Ax - b|| / (||A|| ||x||) in the infinity norm, a
+// backward stable solve keeps it a small multiple of machine epsilon
+func relativeResidual(a [][]float64, x, b []float64) float64 {
+	residual, normA, normX := 0.0, 0.0, 0.0
+	for i := range a {
+		ax, rowSum := 0.0, 0.0
+		for j := range a[i] {
+			ax += a[i][j] * x[j]
+			rowSum += math.Abs(a[i][j])
+		}
+		residual = math.Max(residual, math.Abs(ax-b[i]))
+		normA = math.Max(normA, rowSum)
+	}
+	for _, xi := range x {
+		normX = math.Max(normX, math.Abs(xi))
+	}
+	return residual / (normA * normX)
+}
+
+// linear solver times LU with partial pivoting and householder QR of a random
+// matrix separately, then solves Ax = b through each and checks the residual
+func linearSolver(size int) float64 {
+	a := newMatrix[float64](size)
+	b := make([]float64, size)
+
+	rand.Seed(42)
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
+			a[i][j] = rand.Float64()*2 - 1
+		}
+		b[i] = rand.Float64()*2 - 1
+	}
+
+	start := time.Now()
+	lu, perm, err := luDecompose(a)
+	luDuration := time.Since(start)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "lu decomposition failed -> %v\n", err)
+		return float64(luDuration.Nanoseconds()) / 1000000.0
+	}
+
+	start = time.Now()
+	r, reflectors := householderQR(a)
+	qrDuration := time.Since(start)
+
+	start = time.Now()
+	xLU := luSolve(lu, perm, b)
+	xQR := qrSolve(r, reflectors, b)
+	solveDuration := time.Since(start)
+
+	luResidual := relativeResidual(a, xLU, b)
+	qrResidual := relativeResidual(a, xQR, b)
+	const tolerance = 1e-10
+	if luResidual > tolerance || qrResidual > tolerance {
+		fmt.Fprintf(os.Stderr, "linear solve residual too large: lu %.2e, qr %.2e\n", luResidual, qrResidual)
+	}
+	fmt.Fprintf(os.Stderr, "linear solver %dx%d: lu %.3f ms, qr %.3f ms, both solves %.3f ms, residual lu %.1e, qr %.1e\n",
+		size, size, float64(luDuration.Nanoseconds())/1000000.0, float64(qrDuration.Nanoseconds())/1000000.0,
+		float64(solveDuration.Nanoseconds())/1000000.0, luResidual, qrResidual)
+
+	return float64((luDuration + qrDuration + solveDuration).Nanoseconds()) / 1000000.0
+}
+
+// xoshiro256 is xoshiro256** by blackman and vigna, seeded through splitmix64
+type xoshiro256 struct {
+	s [4]uint64
+}
+
+func newXoshiro256(seed uint64) *xoshiro256 {
+	x := &xoshiro256{}
+	for i := range x.s {
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+		z = (z ^ z>>27) * 0x94d049bb133111eb
+		x.s[i] = z ^ z>>31
+	}
+	return x
+}
+
+func (x *xoshiro256) Uint64() uint64 {
+	result := bits.RotateLeft64(x.s[1]*5, 7) * 9
+	t := x.s[1] << 17
+	x.s[2] ^= x.s[0]
+	x.s[3] ^= x.s[1]
+	x.s[1] ^= x.s[2]
+	x.s[0] ^= x.s[3]
+	x.s[2] ^= t
+	x.s[3] = bits.RotateLeft64(x.s[3], 45)
+	return result
+}
+
+// Float64 takes the top 53 bits, uniform in [0, 1)
+func (x *xoshiro256) Float64() float64 {
+	return float64(x.Uint64()>>11) / (1 << 53)
+}
+
+// cryptoStream reads crypto/rand in 4KB chunks and hands them out 8 bytes
+// at a time, reading a syscall's worth per number would only measure that.
+// a failed read is kept in err and the rest of the run yields zeros
+type cryptoStream struct {
+	buf []byte
+	pos int
+	err error
+}
+
+func (c *cryptoStream) Uint64() uint64 {
+	if c.pos == len(c.buf) {
+		if c.buf == nil {
+			c.buf = make([]byte, 4096)
+		}
+		if _, err := cryptorand.Read(c.buf); err != nil && c.err == nil {
+			c.err = err
+		}
+		c.pos = 0
+	}
+	v := binary.LittleEndian.Uint64(c.buf[c.pos:])
+	c.pos += 8
+	return v
+}
+
+func (c *cryptoStream) Float64() float64 {
+	return float64(c.Uint64()>>11) / (1 << 53)
+}
+
+// rng comparison fills a slice of integers and one of floats from each
+// generator, then estimates pi from the floats as a sanity check that none
+// of them is badly broken
+func rngComparison(count int) float64 {
+	pcg := randv2.New(randv2.NewPCG(42, 54))
+	var chachaSeed [32]byte
+	chachaSeed[0] = 42
+	chacha := randv2.New(randv2.NewChaCha8(chachaSeed))
+	local := rand.New(rand.NewSource(42))
+	xoshiro := newXoshiro256(42)
+	crypto := &cryptoStream{}
+
+	generators := []struct {
+		name    string
+		uint64  func() uint64
+		float64 func() float64
+		err     func() error
+	}{
+		{"math/rand global", rand.Uint64, rand.Float64, nil},
+		{"math/rand local", local.Uint64, local.Float64, nil},
+		{"math/rand/v2 PCG", pcg.Uint64, pcg.Float64, nil},
+		{"math/rand/v2 ChaCha8", chacha.Uint64, chacha.Float64, nil},
+		{"xoshiro256**", xoshiro.Uint64, xoshiro.Float64, nil},
+		{"crypto/rand", crypto.Uint64, crypto.Float64, func() error { return crypto.err }},
+	}
+
+	ints := make([]uint64, count)
+	floats := make([]float64, count)
+	totalTime := 0.0
+	for _, g := range generators {
+		start := time.Now()
+		for i := range ints {
+			ints[i] = g.uint64()
+		}
+		intDuration := time.Since(start)
+
+		start = time.Now()
+		for i := range floats {
+			floats[i] = g.float64()
+		}
+		floatDuration := time.Since(start)
+		if g.err != nil && g.err() != nil {
+			fmt.Fprintf(os.Stderr, "rng %-22s skipped: %v\n", g.name, g.err())
+			continue
+		}
+		totalTime += float64((intDuration + floatDuration).Nanoseconds()) / 1000000.0
+
+		inside := 0
+		for i := 0; i+1 < count; i += 2 {
+			if floats[i]*floats[i]+floats[i+1]*floats[i+1] <= 1 {
+				inside++
+			}
+		}
+		fmt.Fprintf(os.Stderr, "rng %-22s uint64 %6.2f ns, float64 %6.2f ns, pi estimate %.4f\n",
+			g.name, float64(intDuration.Nanoseconds())/float64(count), float64(floatDuration.Nanoseconds())/float64(count),
+			8*float64(inside)/float64(count))
+	}
+	return totalTime
+}
+
+// libraryComparison is set by the gonum build tag to time the matrix, fft
+// and statistics workloads through gonum next to the hand-written code, it
+// only reports on stderr and stays out of the total
+var libraryComparison func(scaleFactor, size, block int)
+
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -119,6 +693,461 @@ func factorize(n int) []int {
 	return factors
 }
 
+// mulMod is a*b mod m without overflow through the 128 bit product
+func mulMod(a, b, m uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	return bits.Rem64(hi, lo, m)
+}
+
+func powMod(base, exp, m uint64) uint64 {
+	result := uint64(1) % m
+	base %= m
+	for exp > 0 {
+		if exp&1 == 1 {
+			result = mulMod(result, base, m)
+		}
+		base = mulMod(base, base, m)
+		exp >>= 1
+	}
+	return result
+}
+
+// millerRabin is deterministic for every 64 bit n, the first twelve primes
+// as witnesses are enough below 3.3e24
+func millerRabin(n uint64) bool {
+	if n < 2 {
+		return false
+	}
+	witnesses := []uint64{2, 3, 5, 7, 11, 13, 17, 19, 23, 29, 31, 37}
+	for _, p := range witnesses {
+		if n%p == 0 {
+			return n == p
+		}
+	}
+
+	d, r := n-1, 0
+	for d%2 == 0 {
+		d /= 2
+		r++
+	}
+	for _, a := range witnesses {
+		x := powMod(a, d, n)
+		if x == 1 || x == n-1 {
+			continue
+		}
+		composite := true
+		for i := 1; i < r; i++ {
+			x = mulMod(x, x, n)
+			if x == n-1 {
+				composite = false
+				break
+			}
+		}
+		if composite {
+			return false
+		}
+	}
+	return true
+}
+
+func gcd(a, b uint64) uint64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// pollardRho finds a non-trivial factor of composite n with brent's cycle
+// detection, batching 128 differences per gcd and retrying with a new
+// constant when a batch collapses to n
+func pollardRho(n uint64, rng *rand.Rand) uint64 {
+	if n%2 == 0 {
+		return 2
+	}
+	for {
+		c := rng.Uint64()%(n-1) + 1
+		f := func(x uint64) uint64 { return (mulMod(x, x, n) + c) % n }
+		y, g, q := rng.Uint64()%n, uint64(1), uint64(1)
+		var x, ys uint64
+		for r := 1; g == 1; r *= 2 {
+			x = y
+			for i := 0; i < r; i++ {
+				y = f(y)
+			}
+			for k := 0; k < r && g == 1; k += 128 {
+				ys = y
+				for i := 0; i < min(128, r-k); i++ {
+					y = f(y)
+					diff := x - y
+					if y > x {
+						diff = y - x
+					}
+					q = mulMod(q, diff, n)
+				}
+				g = gcd(q, n)
+			}
+		}
+		if g == n {
+			// the batch overshot, step back one difference at a time
+			for g = 1; g == 1; {
+				ys = f(ys)
+				diff := x - ys
+				if ys > x {
+					diff = ys - x
+				}
+				g = gcd(diff, n)
+			}
+		}
+		if g != n {
+			return g
+		}
+	}
+}
+
+// factor64 fully factors n with miller-rabin and pollard rho
+func factor64(n uint64, rng *rand.Rand) []uint64 {
+	if n == 1 {
+		return nil
+	}
+	if millerRabin(n) {
+		return []uint64{n}
+	}
+	d := pollardRho(n, rng)
+	return append(factor64(d, rng), factor64(n/d, rng)...)
+}
+
+// randomPrime draws odd numbers of the given bit length until one is prime
+func randomPrime(bitLength int, rng *rand.Rand) uint64 {
+	for {
+		candidate := rng.Uint64()>>(64-bitLength) | 1<<(bitLength-1) | 1
+		if millerRabin(candidate) {
+			return candidate
+		}
+	}
+}
+
+// totient64 is euler's phi from the factorization of n
+func totient64(n uint64, rng *rand.Rand) uint64 {
+	phi := n
+	seen := map[uint64]bool{}
+	for _, p := range factor64(n, rng) {
+		if !seen[p] {
+			seen[p] = true
+			phi = phi / p * (p - 1)
+		}
+	}
+	return phi
+}
+
+// modInverse64 is a^-1 mod m by the extended euclidean algorithm, a and m coprime
+func modInverse64(a, m uint64) uint64 {
+	oldR, r := int64(a%m), int64(m)
+	oldS, s := int64(1), int64(0)
+	for r != 0 {
+		q := oldR / r
+		oldR, r = r, oldR-q*r
+		oldS, s = s, oldS-q*s
+	}
+	if oldS < 0 {
+		oldS += int64(m)
+	}
+	return uint64(oldS)
+}
+
+// crt64 rebuilds x mod the product of pairwise coprime moduli from its
+// residues, the product has to fit in 63 bits
+func crt64(residues, moduli []uint64) uint64 {
+	product := uint64(1)
+	for _, m := range moduli {
+		product *= m
+	}
+	x := uint64(0)
+	for i, m := range moduli {
+		partial := product / m
+		term := mulMod(mulMod(residues[i], modInverse64(partial%m, m), product), partial, product)
+		x = (x + term) % product
+	}
+	return x
+}
+
+// bigPollardRho is pollard rho with floyd's cycle detection on big.Int
+func bigPollardRho(n *big.Int, rng *rand.Rand) *big.Int {
+	one := big.NewInt(1)
+	if n.Bit(0) == 0 {
+		return big.NewInt(2)
+	}
+	x, y, c, d, diff := new(big.Int), new(big.Int), new(big.Int), new(big.Int), new(big.Int)
+	for {
+		c.SetUint64(rng.Uint64()%(n.Uint64()-1) + 1)
+		x.SetUint64(rng.Uint64() % n.Uint64())
+		y.Set(x)
+		d.SetInt64(1)
+		for d.Cmp(one) == 0 {
+			x.Mul(x, x).Add(x, c).Mod(x, n)
+			y.Mul(y, y).Add(y, c).Mod(y, n)
+			y.Mul(y, y).Add(y, c).Mod(y, n)
+			d.GCD(nil, nil, diff.Sub(x, y).Abs(diff), n)
+		}
+		if d.Cmp(n) != 0 {
+			return d
+		}
+	}
+}
+
+func bigFactor(n *big.Int, rng *rand.Rand) []*big.Int {
+	if n.Cmp(big.NewInt(1)) == 0 {
+		return nil
+	}
+	// ProbablyPrime(0) is exact below 2^64
+	if n.ProbablyPrime(0) {
+		return []*big.Int{new(big.Int).Set(n)}
+	}
+	d := bigPollardRho(n, rng)
+	return append(bigFactor(d, rng), bigFactor(new(big.Int).Div(n, d), rng)...)
+}
+
+func bigTotient(n *big.Int, rng *rand.Rand) *big.Int {
+	phi := new(big.Int).Set(n)
+	seen := map[string]bool{}
+	for _, p := range bigFactor(n, rng) {
+		if key := p.String(); !seen[key] {
+			seen[key] = true
+			phi.Div(phi, p).Mul(phi, new(big.Int).Sub(p, big.NewInt(1)))
+		}
+	}
+	return phi
+}
+
+func bigCRT(residues, moduli []*big.Int) *big.Int {
+	product := big.NewInt(1)
+	for _, m := range moduli {
+		product.Mul(product, m)
+	}
+	x, partial, term := new(big.Int), new(big.Int), new(big.Int)
+	for i, m := range moduli {
+		partial.Div(product, m)
+		term.ModInverse(term.Mod(partial, m), m)
+		term.Mul(term, residues[i]).Mul(term, partial)
+		x.Add(x, term)
+	}
+	return x.Mod(x, product)
+}
+
+// modularPhase runs the same modexps, totients and CRT reconstructions on
+// uint64 with math/bits and again on math/big, checking they agree, and
+// returns how long each path took
+func modularPhase(modexps, totients, crts int, seed int64) (time.Duration, time.Duration) {
+	rng := rand.New(rand.NewSource(seed))
+	type crtCase struct {
+		x                uint64
+		residues, moduli []uint64
+	}
+	bases, exps, mods := make([]uint64, modexps), make([]uint64, modexps), make([]uint64, modexps)
+	for i := range bases {
+		bases[i], exps[i], mods[i] = rng.Uint64(), rng.Uint64(), rng.Uint64()>>2|1
+	}
+	numbers := make([]uint64, totients)
+	for i := range numbers {
+		numbers[i] = rng.Uint64()>>24 | 1<<39
+	}
+	cases := make([]crtCase, crts)
+	for i := range cases {
+		// four distinct 15 bit primes multiply to under 2^60
+		seen := map[uint64]bool{}
+		product := uint64(1)
+		for len(cases[i].moduli) < 4 {
+			if p := randomPrime(15, rng); !seen[p] {
+				seen[p] = true
+				cases[i].moduli = append(cases[i].moduli, p)
+				product *= p
+			}
+		}
+		cases[i].x = rng.Uint64() % product
+		for _, m := range cases[i].moduli {
+			cases[i].residues = append(cases[i].residues, cases[i].x%m)
+		}
+	}
+
+	start := time.Now()
+	powers := make([]uint64, modexps)
+	for i := range powers {
+		powers[i] = powMod(bases[i], exps[i], mods[i])
+	}
+	phis := make([]uint64, totients)
+	factorRng := rand.New(rand.NewSource(seed + 1))
+	for i, n := range numbers {
+		phis[i] = totient64(n, factorRng)
+	}
+	crtFailures := 0
+	for _, c := range cases {
+		if crt64(c.residues, c.moduli) != c.x {
+			crtFailures++
+		}
+	}
+	fastDuration := time.Since(start)
+
+	start = time.Now()
+	mismatches := 0
+	result, b, e, m := new(big.Int), new(big.Int), new(big.Int), new(big.Int)
+	for i := range powers {
+		result.Exp(b.SetUint64(bases[i]), e.SetUint64(exps[i]), m.SetUint64(mods[i]))
+		if result.Uint64() != powers[i] {
+			mismatches++
+		}
+	}
+	factorRng = rand.New(rand.NewSource(seed + 1))
+	for i, n := range numbers {
+		if bigTotient(new(big.Int).SetUint64(n), factorRng).Uint64() != phis[i] {
+			mismatches++
+		}
+	}
+	for _, c := range cases {
+		residues, moduli := make([]*big.Int, len(c.moduli)), make([]*big.Int, len(c.moduli))
+		for j := range c.moduli {
+			residues[j], moduli[j] = new(big.Int).SetUint64(c.residues[j]), new(big.Int).SetUint64(c.moduli[j])
+		}
+		if bigCRT(residues, moduli).Uint64() != c.x {
+			crtFailures++
+		}
+	}
+	bigDuration := time.Since(start)
+
+	if mismatches > 0 || crtFailures > 0 {
+		fmt.Fprintf(os.Stderr, "modular arithmetic: %d uint64/big mismatches, %d crt reconstructions wrong\n", mismatches, crtFailures)
+	}
+	return fastDuration, bigDuration
+}
+
+// segmentedPrimeCount counts the primes in [lo, hi) holding only the base
+// primes up to sqrt(hi) and one segment of segmentSize flags at a time, so
+// memory is O(sqrt(hi)) however far out the range sits. checkSegment is
+// called with the first segment's primes so they can be verified
+func segmentedPrimeCount(lo, hi uint64, segmentSize int, checkSegment func(primes []uint64)) int {
+	root := uint64(math.Sqrt(float64(hi))) + 1
+	small := make([]bool, root+1)
+	var basePrimes []uint64
+	for i := uint64(2); i <= root; i++ {
+		if !small[i] {
+			basePrimes = append(basePrimes, i)
+			for j := i * i; j <= root; j += i {
+				small[j] = true
+			}
+		}
+	}
+
+	count := 0
+	composite := make([]bool, segmentSize)
+	for segLo := lo; segLo < hi; segLo += uint64(segmentSize) {
+		segHi := segLo + uint64(segmentSize)
+		if segHi > hi {
+			segHi = hi
+		}
+		span := int(segHi - segLo)
+		clear(composite[:span])
+
+		for _, p := range basePrimes {
+			if p*p >= segHi {
+				break
+			}
+			// first multiple of p in the segment, never p itself
+			start := max((segLo+p-1)/p*p, p*p)
+			for j := start; j < segHi; j += p {
+				composite[j-segLo] = true
+			}
+		}
+
+		var found []uint64
+		for i := 0; i < span; i++ {
+			if n := segLo + uint64(i); !composite[i] && n >= 2 {
+				count++
+				if checkSegment != nil {
+					found = append(found, n)
+				}
+			}
+		}
+		if checkSegment != nil {
+			checkSegment(found)
+			checkSegment = nil
+		}
+	}
+	return count
+}
+
+// primality phases run miller-rabin over random odd 64-bit numbers and
+// pollard rho over semiprimes of two 31-bit primes, go only
+func primalityPhases(limit int) float64 {
+	rng := rand.New(rand.NewSource(42))
+	phaseStart := time.Now()
+	probablePrimes := 0
+	for i := 0; i < limit/4; i++ {
+		if millerRabin(rng.Uint64() | 1) {
+			probablePrimes++
+		}
+	}
+	millerRabinDuration := time.Since(phaseStart)
+
+	// trial division is the reference for the small numbers
+	for n := 0; n <= min(limit, 10000); n++ {
+		if millerRabin(uint64(n)) != isPrimeFast(int64(n)) {
+			fmt.Fprintf(os.Stderr, "miller-rabin disagrees with trial division at %d\n", n)
+			break
+		}
+	}
+
+	semiprimes := max(limit/4000, 1)
+	factorRng := rand.New(rand.NewSource(7))
+	var rhoDuration time.Duration
+	for i := 0; i < semiprimes; i++ {
+		p, q := randomPrime(31, rng), randomPrime(31, rng)
+		phaseStart = time.Now()
+		factors := factor64(p*q, factorRng)
+		rhoDuration += time.Since(phaseStart)
+		if len(factors) != 2 || factors[0]*factors[1] != p*q || (factors[0] != p && factors[0] != q) {
+			fmt.Fprintf(os.Stderr, "pollard rho factored %d = %d*%d as %v\n", p*q, p, q, factors)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "number theory: miller-rabin %d numbers %.3f ms (%d prime), pollard rho %d semiprimes %.3f ms\n",
+		limit/4, float64(millerRabinDuration.Nanoseconds())/1000000.0, probablePrimes, semiprimes,
+		float64(rhoDuration.Nanoseconds())/1000000.0)
+	return float64((millerRabinDuration + rhoDuration).Nanoseconds()) / 1000000.0
+}
+
+// modular arithmetic sizes its modexps, totients and crts off limit and
+// reports both paths on stderr, go only
+func modularArithmetic(limit int) float64 {
+	modularDuration, modularBigDuration := modularPhase(limit/8, limit/800, limit/80, 42)
+	fmt.Fprintf(os.Stderr, "modular arithmetic: %d modexps, %d totients, %d crts: uint64 %.3f ms, math/big %.3f ms\n",
+		limit/8, limit/800, limit/80, float64(modularDuration.Nanoseconds())/1000000.0, float64(modularBigDuration.Nanoseconds())/1000000.0)
+	return float64((modularDuration + modularBigDuration).Nanoseconds()) / 1000000.0
+}
+
+// segmented sieve phase counts the primes in a window just above 10^10,
+// far past what a plain sieve could hold in memory, go only
+func segmentedSievePhase(limit int) float64 {
+	const segmentedLo = 10_000_000_000
+	window := uint64(limit) * 100
+	phaseStart := time.Now()
+	segmentedPrimes := segmentedPrimeCount(segmentedLo, segmentedLo+window, 32*1024, func(primes []uint64) {
+		// miller-rabin is the reference for the first segment
+		next := 0
+		for n := uint64(segmentedLo); n < segmentedLo+32*1024; n++ {
+			isSegmentPrime := next < len(primes) && primes[next] == n
+			if isSegmentPrime {
+				next++
+			}
+			if millerRabin(n) != isSegmentPrime {
+				fmt.Fprintf(os.Stderr, "segmented sieve disagrees with miller-rabin at %d\n", n)
+				return
+			}
+		}
+	})
+	segmentedDuration := time.Since(phaseStart)
+	fmt.Fprintf(os.Stderr, "number theory: segmented sieve of [%d, %d) %.3f ms, %d primes\n",
+		uint64(segmentedLo), segmentedLo+window, float64(segmentedDuration.Nanoseconds())/1000000.0, segmentedPrimes)
+	return float64(segmentedDuration.Nanoseconds()) / 1000000.0
+}
+
 func numberTheory(limit int) float64 {
 	start := time.Now()
 	
@@ -165,7 +1194,165 @@ func numberTheory(limit int) float64 {
 	return float64(duration.Nanoseconds()) / 1000000.0
 }
 
-func statisticalComputing(samples int) float64 {
+// meanVariance is the two pass mean and population variance
+func meanVariance(values []float64) (float64, float64) {
+	mean := 0.0
+	for _, val := range values {
+		mean += val
+	}
+	mean /= float64(len(values))
+
+	variance := 0.0
+	for _, val := range values {
+		diff := val - mean
+		variance += diff * diff
+	}
+	variance /= float64(len(values))
+	return mean, variance
+}
+
+func normalCDF(x float64) float64 {
+	return 0.5 * math.Erfc(-x/math.Sqrt2)
+}
+
+// kolmogorovSmirnov sorts values in place and walks the empirical cdf
+// against cdf, returning the largest gap and its asymptotic p-value
+func kolmogorovSmirnov(values []float64, cdf func(float64) float64) (float64, float64) {
+	sort.Float64s(values)
+	n := float64(len(values))
+	d := 0.0
+	for i, v := range values {
+		// the ecdf steps from i/n to (i+1)/n at v, check both sides
+		f := cdf(v)
+		d = math.Max(d, math.Max(float64(i+1)/n-f, f-float64(i)/n))
+	}
+
+	// kolmogorov distribution tail with stephens' small sample correction
+	sqrtN := math.Sqrt(n)
+	lambda := (sqrtN + 0.12 + 0.11/sqrtN) * d
+	p := 0.0
+	for k := 1; k <= 100; k++ {
+		term := 2 * math.Exp(-2*float64(k*k)*lambda*lambda)
+		if k%2 == 0 {
+			term = -term
+		}
+		p += term
+		if math.Abs(term) < 1e-12 {
+			break
+		}
+	}
+	return d, math.Min(math.Max(p, 0), 1)
+}
+
+// chiSquareNormal bins values into equal width bins over [-3, 3] plus the
+// two tails and compares the counts with what a standard normal expects,
+// returning the statistic and its degrees of freedom
+func chiSquareNormal(values []float64, bins int) (float64, int) {
+	counts := make([]int, bins+2)
+	width := 6.0 / float64(bins)
+	for _, v := range values {
+		switch {
+		case v < -3:
+			counts[0]++
+		case v >= 3:
+			counts[bins+1]++
+		default:
+			counts[1+int((v+3)/width)]++
+		}
+	}
+
+	n := float64(len(values))
+	statistic := 0.0
+	for i, count := range counts {
+		lo, hi := math.Inf(-1), math.Inf(1)
+		if i > 0 {
+			lo = -3 + float64(i-1)*width
+		}
+		if i <= bins {
+			hi = -3 + float64(i)*width
+		}
+		expected := n * (normalCDF(hi) - normalCDF(lo))
+		diff := float64(count) - expected
+		statistic += diff * diff / expected
+	}
+	return statistic, len(counts) - 1
+}
+
+// radicalInverse mirrors the base b digits of i around the radix point,
+// the van der corput sequence that halton uses one prime base per dimension
+func radicalInverse(i uint64, base uint64) float64 {
+	result, scale := 0.0, 1.0/float64(base)
+	for ; i > 0; i /= base {
+		result += float64(i%base) * scale
+		scale /= float64(base)
+	}
+	return result
+}
+
+// halton2D yields points of the base 2, 3 halton sequence, skipping index 0
+type halton2D struct {
+	i uint64
+}
+
+func (h *halton2D) next() (float64, float64) {
+	h.i++
+	return radicalInverse(h.i, 2), radicalInverse(h.i, 3)
+}
+
+// sobol2D is the first two sobol dimensions in gray code order, the first
+// is van der corput in base 2 and the second comes from the primitive
+// polynomial x+1
+type sobol2D struct {
+	n         uint32
+	x         [2]uint32
+	direction [2][32]uint32
+}
+
+func newSobol2D() *sobol2D {
+	s := &sobol2D{}
+	for k := 0; k < 32; k++ {
+		s.direction[0][k] = 1 << (31 - k)
+	}
+	s.direction[1][0] = 1 << 31
+	for k := 1; k < 32; k++ {
+		s.direction[1][k] = s.direction[1][k-1] ^ s.direction[1][k-1]>>1
+	}
+	return s
+}
+
+func (s *sobol2D) next() (float64, float64) {
+	// flip the direction number for the lowest zero bit of n
+	c := bits.TrailingZeros32(^s.n)
+	s.n++
+	s.x[0] ^= s.direction[0][c]
+	s.x[1] ^= s.direction[1][c]
+	return float64(s.x[0]) / (1 << 32), float64(s.x[1]) / (1 << 32)
+}
+
+// estimatePiAndIntegral is the monte carlo pi and the integral of sin over
+// [0, pi/2] from statisticalComputing, drawing points from next
+func estimatePiAndIntegral(next func() (float64, float64), samples, integrationSamples int) (float64, float64) {
+	insideCircle := 0
+	for i := 0; i < samples; i++ {
+		x, y := next()
+		if x*x+y*y <= 1.0 {
+			insideCircle++
+		}
+	}
+
+	integralSum := 0.0
+	for i := 0; i < integrationSamples; i++ {
+		x, _ := next()
+		integralSum += math.Sin(x * math.Pi / 2)
+	}
+	return 4.0 * float64(insideCircle) / float64(samples), (math.Pi / 2) * integralSum / float64(integrationSamples)
+}
+
+// statistical computing times the pseudo-random workload like the other
+// languages, then the pi and integral estimates again with pseudo-random,
+// halton and sobol points, which are reported on their own. with verify the
+// error of each estimate is printed too
+func statisticalComputing(samples int, verify bool) float64 {
 	start := time.Now()
 	
 	rand.Seed(42)
@@ -192,18 +1379,7 @@ func statisticalComputing(samples int) float64 {
 	piEstimate := 4.0 * float64(insideCircle) / float64(samples)
 	
 	// statistical calculations
-	mean := 0.0
-	for _, val := range values {
-		mean += val
-	}
-	mean /= float64(len(values))
-	
-	variance := 0.0
-	for _, val := range values {
-		diff := val - mean
-		variance += diff * diff
-	}
-	variance /= float64(len(values))
+	_, variance := meanVariance(values)
 	
 	// numerical integration
 	integrationSamples := samples / 4
@@ -212,32 +1388,114 @@ func statisticalComputing(samples int) float64 {
 		x := rand.Float64() * math.Pi / 2
 		integralSum += math.Sin(x)
 	}
-	integralResult := (math.Pi / 2) * integralSum / float64(integrationSamples)
-	
+	integralResult := (math.Pi / 2) * integralSum / float64(integrationSamples)
+	
+	duration := time.Since(start)
+	result := piEstimate + variance + integralResult
+	_ = result
+
+	// goodness of fit against the standard normal, after the timed region
+	ksD, ksP := kolmogorovSmirnov(values, normalCDF)
+	chiSquare, chiSquareDOF := chiSquareNormal(values, 40)
+	fmt.Fprintf(os.Stderr, "normal samples %d: ks D %.5f (p %.3f), chi-square %.2f on %d dof\n",
+		len(values), ksD, ksP, chiSquare, chiSquareDOF)
+
+	pseudo := rand.New(rand.NewSource(42))
+	estimators := []struct {
+		name string
+		next func() (float64, float64)
+	}{
+		{"pseudo-random", func() (float64, float64) { return pseudo.Float64(), pseudo.Float64() }},
+		{"halton", (&halton2D{}).next},
+		{"sobol", newSobol2D().next},
+	}
+	for _, e := range estimators {
+		estimateStart := time.Now()
+		pi, integral := estimatePiAndIntegral(e.next, samples, integrationSamples)
+		estimateDuration := time.Since(estimateStart)
+		fmt.Fprintf(os.Stderr, "monte carlo %-14s %d points: %.3f ms", e.name, samples+integrationSamples,
+			float64(estimateDuration.Nanoseconds())/1000000.0)
+		if verify {
+			fmt.Fprintf(os.Stderr, ", pi error %.2e, integral error %.2e", math.Abs(pi-math.Pi), math.Abs(integral-1))
+		}
+		fmt.Fprintln(os.Stderr)
+	}
+
+	return float64(duration.Nanoseconds()) / 1000000.0
+}
+
+// mixture target for the sampler, two gaussians far enough apart that the
+// chain has to cross the low density gap between them
+var mixtureComponents = []struct {
+	weight, mean, sd float64
+}{
+	{0.3, -2.0, 0.5},
+	{0.7, 3.0, 1.0},
+}
+
+// mixtureDensity is unnormalized, metropolis-hastings only needs ratios
+func mixtureDensity(x float64) float64 {
+	density := 0.0
+	for _, c := range mixtureComponents {
+		z := (x - c.mean) / c.sd
+		density += c.weight / c.sd * math.Exp(-0.5*z*z)
+	}
+	return density
+}
+
+// mcmcSampling runs a random walk metropolis-hastings chain until it has
+// accepted a fixed number of moves, so the time covers however many
+// proposals it takes to get there
+func mcmcSampling(accepted int) float64 {
+	rng := rand.New(rand.NewSource(42))
+	const step = 2.5
+
+	start := time.Now()
+	x, density := 0.0, mixtureDensity(0)
+	proposals, sum := 0, 0.0
+	for n := 0; n < accepted; {
+		proposals++
+		candidate := x + step*rng.NormFloat64()
+		candidateDensity := mixtureDensity(candidate)
+		if candidateDensity >= density || rng.Float64()*density < candidateDensity {
+			x, density = candidate, candidateDensity
+			n++
+		}
+		// rejections repeat the current state, so every step counts
+		sum += x
+	}
 	duration := time.Since(start)
-	result := piEstimate + variance + integralResult
-	_ = result
-	
+
+	exactMean := 0.0
+	for _, c := range mixtureComponents {
+		exactMean += c.weight * c.mean
+	}
+	fmt.Fprintf(os.Stderr, "mcmc %d accepted of %d proposals (%.1f%% acceptance), mean %.4f (exact %.4f): %.3f ms\n",
+		accepted, proposals, 100*float64(accepted)/float64(proposals), sum/float64(proposals), exactMean,
+		float64(duration.Nanoseconds())/1000000.0)
+
 	return float64(duration.Nanoseconds()) / 1000000.0
 }
 
-func fft(data []complex128) {
+// fftRecursive is the original radix-2 fft, it allocates even/odd halves at
+// every level so it's mostly measuring the allocator
+func fftRecursive(data []complex128) {
 	n := len(data)
 	if n <= 1 {
 		return
 	}
-	
+
 	even := make([]complex128, n/2)
 	odd := make([]complex128, n/2)
-	
+
 	for i := 0; i < n/2; i++ {
 		even[i] = data[i*2]
 		odd[i] = data[i*2+1]
 	}
-	
-	fft(even)
-	fft(odd)
-	
+
+	fftRecursive(even)
+	fftRecursive(odd)
+
 	for i := 0; i < n/2; i++ {
 		t := cmplx.Exp(complex(0, -2*math.Pi*float64(i)/float64(n))) * odd[i]
 		data[i] = even[i] + t
@@ -245,73 +1503,637 @@ func fft(data []complex128) {
 	}
 }
 
+// fftPlan holds the bit reversal permutation and twiddle factors for one
+// power of two size, so repeated transforms only do the butterflies
+type fftPlan struct {
+	rev      []int
+	twiddles []complex128
+}
+
+var fftPlans = map[int]*fftPlan{}
+
+func planFFT(n int) *fftPlan {
+	if plan, ok := fftPlans[n]; ok {
+		return plan
+	}
+
+	bits := 0
+	for 1<<bits < n {
+		bits++
+	}
+	plan := &fftPlan{rev: make([]int, n), twiddles: make([]complex128, n/2)}
+	for i := range plan.rev {
+		r := 0
+		for b := 0; b < bits; b++ {
+			r |= (i >> b & 1) << (bits - 1 - b)
+		}
+		plan.rev[i] = r
+	}
+	for k := range plan.twiddles {
+		plan.twiddles[k] = cmplx.Exp(complex(0, -2*math.Pi*float64(k)/float64(n)))
+	}
+	fftPlans[n] = plan
+	return plan
+}
+
+// iterative in-place cooley-tukey, len(data) must be a power of two
+func fft(data []complex128) {
+	n := len(data)
+	if n <= 1 {
+		return
+	}
+	plan := planFFT(n)
+
+	for i, r := range plan.rev {
+		if i < r {
+			data[i], data[r] = data[r], data[i]
+		}
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		half := size / 2
+		step := n / size
+		for start := 0; start < n; start += size {
+			for k := 0; k < half; k++ {
+				t := plan.twiddles[k*step] * data[start+k+half]
+				u := data[start+k]
+				data[start+k] = u + t
+				data[start+k+half] = u - t
+			}
+		}
+	}
+}
+
 func ifft(data []complex128) {
+	inverseWith(fft, data)
+}
+
+// inverseWith runs the inverse transform through the conjugate trick on
+// whichever forward fft is given
+func inverseWith(transform func([]complex128), data []complex128) {
 	n := len(data)
 	for i := range data {
 		data[i] = cmplx.Conj(data[i])
 	}
-	fft(data)
+	transform(data)
 	for i := range data {
 		data[i] = cmplx.Conj(data[i]) / complex(float64(n), 0)
 	}
 }
 
-func signalProcessing(size int) float64 {
-	signal := make([]complex128, size)
-	kernel := make([]complex128, size)
-	result := make([]complex128, size)
-	
-	rand.Seed(42)
-	for i := 0; i < size; i++ {
-		real := rand.Float64()*2 - 1
-		imag := rand.Float64()*2 - 1
-		signal[i] = complex(real, imag)
-		kernel[i] = complex(rand.Float64()*2-1, 0)
+// nextPowerOfTwo rounds n up, the iterative fft only takes power of two sizes
+func nextPowerOfTwo(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
 	}
-	
-	start := time.Now()
-	
+	return p
+}
+
+// convolveAndRoundTrip is the signal processing workload with a given forward
+// fft: a frequency domain convolution plus a forward/inverse round trip
+func convolveAndRoundTrip(transform func([]complex128), signal, kernel []complex128) ([]complex128, float64) {
+	size := len(signal)
+
 	// prepare fft data
 	signalFFT := make([]complex128, size)
 	kernelFFT := make([]complex128, size)
+	result := make([]complex128, size)
 	copy(signalFFT, signal)
 	copy(kernelFFT, kernel)
-	
+
 	// forward fft
-	fft(signalFFT)
-	fft(kernelFFT)
-	
+	transform(signalFFT)
+	transform(kernelFFT)
+
 	// convolution in frequency domain
 	for i := 0; i < size; i++ {
 		result[i] = signalFFT[i] * kernelFFT[i]
 	}
-	
+
 	// inverse fft
-	ifft(result)
-	
+	inverseWith(transform, result)
+
 	// round trip test
 	roundtrip := make([]complex128, size)
 	copy(roundtrip, signal)
-	fft(roundtrip)
-	ifft(roundtrip)
-	
+	transform(roundtrip)
+	inverseWith(transform, roundtrip)
+
 	errorSum := 0.0
 	for i := 0; i < size; i++ {
 		errorSum += cmplx.Abs(roundtrip[i] - signal[i])
 	}
-	
+	return result, errorSum
+}
+
+// rfft transforms real input of power of two length n through one complex
+// fft of length n/2, packing even samples as real parts and odd samples as
+// imaginary parts, then splitting the halves apart with conjugate symmetry.
+// only the n/2+1 non redundant bins are returned
+func rfft(x []float64) []complex128 {
+	n := len(x)
+	m := n / 2
+	if m == 0 {
+		return []complex128{complex(x[0], 0)}
+	}
+
+	z := make([]complex128, m)
+	for k := range z {
+		z[k] = complex(x[2*k], x[2*k+1])
+	}
+	fft(z)
+
+	twiddles := planFFT(n).twiddles
+	out := make([]complex128, m+1)
+	for k := 0; k <= m; k++ {
+		zk := z[k%m]
+		zc := cmplx.Conj(z[(m-k)%m])
+		even := (zk + zc) * 0.5
+		odd := (zk - zc) * complex(0, -0.5)
+		w := complex(-1, 0)
+		if k < m {
+			w = twiddles[k]
+		}
+		out[k] = even + w*odd
+	}
+	return out
+}
+
+var dctTwiddles = map[int][]complex128{}
+
+// dct2 is the unnormalized dct-ii, X[k] = sum x[j] cos(pi (2j+1) k / 2n),
+// using makhoul's reordering so it costs one real fft of the same length
+func dct2(x []float64) []float64 {
+	n := len(x)
+	v := make([]float64, n)
+	for j := 0; j < n/2; j++ {
+		v[j] = x[2*j]
+		v[n-1-j] = x[2*j+1]
+	}
+	if n%2 == 1 {
+		v[n/2] = x[n-1]
+	}
+	spectrum := rfft(v)
+
+	twiddles, ok := dctTwiddles[n]
+	if !ok {
+		twiddles = make([]complex128, n)
+		for k := range twiddles {
+			twiddles[k] = cmplx.Exp(complex(0, -math.Pi*float64(k)/float64(2*n)))
+		}
+		dctTwiddles[n] = twiddles
+	}
+
+	out := make([]float64, n)
+	for k := range out {
+		var bin complex128
+		if k <= n/2 {
+			bin = spectrum[k]
+		} else {
+			bin = cmplx.Conj(spectrum[n-k])
+		}
+		out[k] = real(bin * twiddles[k])
+	}
+	return out
+}
+
+// realTransforms runs the complex fft on real data, the packed real fft
+// and the dct-ii over the same signal reps times each. the real transforms
+// are what gets timed, the complex run is the baseline they're compared to
+func realTransforms(size, reps int) float64 {
+	x := make([]float64, size)
+	for i := range x {
+		x[i] = rand.Float64()*2 - 1
+	}
+
+	// build the fft plans and dct twiddles outside the timed loops
+	planFFT(size)
+	dct2(x)
+
+	data := make([]complex128, size)
+	complexStart := time.Now()
+	for r := 0; r < reps; r++ {
+		for i, v := range x {
+			data[i] = complex(v, 0)
+		}
+		fft(data)
+	}
+	complexDuration := time.Since(complexStart)
+
+	var spectrum []complex128
+	realStart := time.Now()
+	for r := 0; r < reps; r++ {
+		spectrum = rfft(x)
+	}
+	realDuration := time.Since(realStart)
+
+	var coefficients []float64
+	dctStart := time.Now()
+	for r := 0; r < reps; r++ {
+		coefficients = dct2(x)
+	}
+	dctDuration := time.Since(dctStart)
+
+	maxDiff := 0.0
+	for k, bin := range spectrum {
+		maxDiff = math.Max(maxDiff, cmplx.Abs(bin-data[k]))
+	}
+	// spot check a few dct bins against the direct sum
+	dctDiff := 0.0
+	for k := 0; k < size; k += max(size/8, 1) {
+		direct := 0.0
+		for j, v := range x {
+			direct += v * math.Cos(math.Pi*float64((2*j+1)*k)/float64(2*size))
+		}
+		dctDiff = math.Max(dctDiff, math.Abs(direct-coefficients[k]))
+	}
+
+	perRep := func(d time.Duration) float64 {
+		return float64(d.Nanoseconds()) / 1000.0 / float64(reps)
+	}
+	fmt.Fprintf(os.Stderr, "real fft size %d: complex %.2f us, real %.2f us (%.1fx), dct-ii %.2f us, max diff %.2e, dct diff %.2e\n",
+		size, perRep(complexDuration), perRep(realDuration), float64(complexDuration)/float64(realDuration),
+		perRep(dctDuration), maxDiff, dctDiff)
+
+	return float64((realDuration + dctDuration).Nanoseconds()) / 1000000.0
+}
+
+// signal processing runs on the iterative fft when the size is a power of
+// two and on the recursive one otherwise. both then run on the size padded
+// to a power of two for comparison, along with the real fft and dct, and
+// those stay out of the total
+func signalProcessing(size int) float64 {
+	signal := make([]complex128, size)
+	kernel := make([]complex128, size)
+
+	rand.Seed(42)
+	for i := 0; i < size; i++ {
+		real := rand.Float64()*2 - 1
+		imag := rand.Float64()*2 - 1
+		signal[i] = complex(real, imag)
+		kernel[i] = complex(rand.Float64()*2-1, 0)
+	}
+
+	// the iterative fft needs a power of two, any other size keeps the
+	// recursive one so the workload matches the other languages
+	transform := fftRecursive
+	if size == nextPowerOfTwo(size) {
+		transform = fft
+	}
+
+	start := time.Now()
+	result, errorSum := convolveAndRoundTrip(transform, signal, kernel)
 	duration := time.Since(start)
-	
+
 	sum := 0.0
 	for _, val := range result {
 		sum += cmplx.Abs(val)
 	}
 	sum += errorSum
 	_ = sum
-	
+
+	// the comparison zero pads up to a power of two so both ffts can run it
+	padded := nextPowerOfTwo(size)
+	paddedSignal := make([]complex128, padded)
+	paddedKernel := make([]complex128, padded)
+	copy(paddedSignal, signal)
+	copy(paddedKernel, kernel)
+
+	iterativeStart := time.Now()
+	iterativeResult, iterativeError := convolveAndRoundTrip(fft, paddedSignal, paddedKernel)
+	iterativeDuration := time.Since(iterativeStart)
+
+	recursiveStart := time.Now()
+	recursiveResult, _ := convolveAndRoundTrip(fftRecursive, paddedSignal, paddedKernel)
+	recursiveDuration := time.Since(recursiveStart)
+
+	maxDiff := 0.0
+	for i := range iterativeResult {
+		maxDiff = math.Max(maxDiff, cmplx.Abs(iterativeResult[i]-recursiveResult[i]))
+	}
+	fmt.Fprintf(os.Stderr, "fft size %d: iterative %.3f ms, recursive %.3f ms (%.1fx), round trip error %.2e, max diff %.2e\n",
+		padded, float64(iterativeDuration.Nanoseconds())/1000000.0, float64(recursiveDuration.Nanoseconds())/1000000.0,
+		float64(recursiveDuration)/float64(iterativeDuration), iterativeError, maxDiff)
+
+	realTransforms(padded, 100)
+
+	return float64(duration.Nanoseconds()) / 1000000.0
+}
+
+// forEachLine hands the n rows or columns of a grid out to workers, each
+// with its own n long scratch buffer for gathering a column
+func forEachLine(n, workers int, fn func(line int, scratch []complex128)) {
+	lines := make(chan int, n)
+	for i := 0; i < n; i++ {
+		lines <- i
+	}
+	close(lines)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			scratch := make([]complex128, n)
+			for line := range lines {
+				fn(line, scratch)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// fft2D transforms a row-major n x n grid in place, rows first and then
+// columns. fftPlans isn't locked so the plan is built before fanning out,
+// the workers only read it
+func fft2D(grid []complex128, n, workers int) {
+	planFFT(n)
+	forEachLine(n, workers, func(row int, _ []complex128) {
+		fft(grid[row*n : (row+1)*n])
+	})
+	forEachLine(n, workers, func(col int, scratch []complex128) {
+		for i := range scratch {
+			scratch[i] = grid[i*n+col]
+		}
+		fft(scratch)
+		for i, v := range scratch {
+			grid[i*n+col] = v
+		}
+	})
+}
+
+// ifft2D is the conjugate trick from ifft on the whole grid
+func ifft2D(grid []complex128, n, workers int) {
+	for i := range grid {
+		grid[i] = cmplx.Conj(grid[i])
+	}
+	fft2D(grid, n, workers)
+	scale := complex(1/float64(n*n), 0)
+	for i := range grid {
+		grid[i] = cmplx.Conj(grid[i]) * scale
+	}
+}
+
+// blurImage circularly convolves a n x n image with a kernel centered on
+// the origin through the frequency domain
+func blurImage(image []float64, kernel []complex128, n, workers int) []float64 {
+	grid := make([]complex128, n*n)
+	for i, v := range image {
+		grid[i] = complex(v, 0)
+	}
+	kernelFFT := make([]complex128, n*n)
+	copy(kernelFFT, kernel)
+
+	fft2D(grid, n, workers)
+	fft2D(kernelFFT, n, workers)
+	for i := range grid {
+		grid[i] *= kernelFFT[i]
+	}
+	ifft2D(grid, n, workers)
+
+	blurred := make([]float64, n*n)
+	for i, v := range grid {
+		blurred[i] = real(v)
+	}
+	return blurred
+}
+
+// imageConvolution blurs a random n x n image with a 5x5 gaussian, serially
+// for the total and then spread over workers for the speedup
+func imageConvolution(n, workers int) float64 {
+	n = nextPowerOfTwo(n)
+	image := make([]float64, n*n)
+	for i := range image {
+		image[i] = rand.Float64()
+	}
+
+	const radius = 2
+	kernel := make([]complex128, n*n)
+	weights := map[[2]int]float64{}
+	weightSum := 0.0
+	for dy := -radius; dy <= radius; dy++ {
+		for dx := -radius; dx <= radius; dx++ {
+			w := math.Exp(-float64(dx*dx+dy*dy) / 2)
+			weights[[2]int{dy, dx}] = w
+			weightSum += w
+		}
+	}
+	for d, w := range weights {
+		weights[d] = w / weightSum
+		// negative offsets wrap around to the far edge
+		kernel[((d[0]+n)%n)*n+(d[1]+n)%n] = complex(w/weightSum, 0)
+	}
+
+	start := time.Now()
+	blurred := blurImage(image, kernel, n, 1)
+	duration := time.Since(start)
+
+	parallelStart := time.Now()
+	parallelBlurred := blurImage(image, kernel, n, workers)
+	parallelDuration := time.Since(parallelStart)
+
+	// spot check some pixels against the direct sum, edges included
+	maxDiff := 0.0
+	for p := 0; p < n*n; p += max(n*n/16, 1) {
+		y, x := p/n, p%n
+		direct := 0.0
+		for d, w := range weights {
+			direct += w * image[((y+d[0]+n)%n)*n+(x+d[1]+n)%n]
+		}
+		maxDiff = math.Max(maxDiff, math.Abs(direct-blurred[p]))
+		maxDiff = math.Max(maxDiff, math.Abs(direct-parallelBlurred[p]))
+	}
+	fmt.Fprintf(os.Stderr, "2d fft blur %dx%d: serial %.3f ms, parallel %.3f ms on %d workers (%.2fx), max diff vs direct %.2e\n",
+		n, n, float64(duration.Nanoseconds())/1000000.0, float64(parallelDuration.Nanoseconds())/1000000.0,
+		workers, float64(duration)/float64(parallelDuration), maxDiff)
+
 	return float64(duration.Nanoseconds()) / 1000000.0
 }
 
+func schoolbookMultiply(a, b []int64) []int64 {
+	if len(a) == 0 || len(b) == 0 {
+		return nil
+	}
+	out := make([]int64, len(a)+len(b)-1)
+	for i, x := range a {
+		for j, y := range b {
+			out[i+j] += x * y
+		}
+	}
+	return out
+}
+
+// karatsubaCutoff is where karatsuba hands off to the schoolbook product
+const karatsubaCutoff = 32
+
+// karatsuba multiplies two polynomials of the same length with three half
+// size products instead of four
+func karatsuba(a, b []int64) []int64 {
+	n := len(a)
+	if n <= karatsubaCutoff {
+		return schoolbookMultiply(a, b)
+	}
+
+	m := n / 2
+	z0 := karatsuba(a[:m], b[:m])
+	z2 := karatsuba(a[m:], b[m:])
+
+	aSum, bSum := make([]int64, n-m), make([]int64, n-m)
+	copy(aSum, a[m:])
+	copy(bSum, b[m:])
+	for i := 0; i < m; i++ {
+		aSum[i] += a[i]
+		bSum[i] += b[i]
+	}
+	z1 := karatsuba(aSum, bSum)
+	for i, v := range z0 {
+		z1[i] -= v
+	}
+	for i, v := range z2 {
+		z1[i] -= v
+	}
+
+	out := make([]int64, 2*n-1)
+	copy(out, z0)
+	for i, v := range z1 {
+		out[i+m] += v
+	}
+	for i, v := range z2 {
+		out[i+2*m] += v
+	}
+	return out
+}
+
+// fftMultiply evaluates both polynomials at the roots of unity, multiplies
+// pointwise and rounds the inverse back to integers, which is exact while
+// the coefficients stay well inside float64 precision
+func fftMultiply(a, b []int64) []int64 {
+	if len(a) == 0 || len(b) == 0 {
+		return nil
+	}
+	resultLen := len(a) + len(b) - 1
+	n := nextPowerOfTwo(resultLen)
+	fa, fb := make([]complex128, n), make([]complex128, n)
+	for i, v := range a {
+		fa[i] = complex(float64(v), 0)
+	}
+	for i, v := range b {
+		fb[i] = complex(float64(v), 0)
+	}
+
+	fft(fa)
+	fft(fb)
+	for i := range fa {
+		fa[i] *= fb[i]
+	}
+	ifft(fa)
+
+	out := make([]int64, resultLen)
+	for i := range out {
+		out[i] = int64(math.Round(real(fa[i])))
+	}
+	return out
+}
+
+// digitsToBig reads little endian base 10 coefficients as an integer,
+// carries included, so a product can be checked against math/big
+func digitsToBig(coefficients []int64) *big.Int {
+	result := new(big.Int)
+	ten := big.NewInt(10)
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		result.Mul(result, ten)
+		result.Add(result, big.NewInt(coefficients[i]))
+	}
+	return result
+}
+
+// polynomialMultiplication multiplies two random digit arrays of length
+// size with each method, checks the products against each other and
+// math/big, then walks power of two lengths to find where karatsuba and the
+// fft overtake the schoolbook product
+func polynomialMultiplication(size int) float64 {
+	randomDigits := func(n int) []int64 {
+		digits := make([]int64, n)
+		for i := range digits {
+			digits[i] = int64(rand.Intn(10))
+		}
+		return digits
+	}
+	a, b := randomDigits(size), randomDigits(size)
+
+	methods := []struct {
+		name     string
+		multiply func(a, b []int64) []int64
+	}{
+		{"schoolbook", schoolbookMultiply},
+		{"karatsuba", karatsuba},
+		{"fft", fftMultiply},
+	}
+
+	totalTime := 0.0
+	var reference []int64
+	for _, m := range methods {
+		start := time.Now()
+		product := m.multiply(a, b)
+		duration := time.Since(start)
+		totalTime += float64(duration.Nanoseconds()) / 1000000.0
+
+		if reference == nil {
+			reference = product
+		}
+		mismatches := 0
+		for i := range reference {
+			if product[i] != reference[i] {
+				mismatches++
+			}
+		}
+		if mismatches > 0 {
+			fmt.Fprintf(os.Stderr, "polynomial %s differs from schoolbook in %d coefficients\n", m.name, mismatches)
+		}
+		fmt.Fprintf(os.Stderr, "polynomial %d x %d %-10s %.3f ms\n", size, size, m.name, float64(duration.Nanoseconds())/1000000.0)
+	}
+
+	expected := new(big.Int).Mul(digitsToBig(a), digitsToBig(b))
+	if digitsToBig(reference).Cmp(expected) != 0 {
+		fmt.Fprintln(os.Stderr, "polynomial product disagrees with math/big")
+	}
+
+	// crossovers are probed outside the total, repeating small sizes so
+	// each timing covers enough work to be measurable
+	crossover := make([]int, len(methods))
+	for n := 8; n <= size; n *= 2 {
+		x, y := randomDigits(n), randomDigits(n)
+		reps := max(1<<18/(n*n), 1)
+		times := make([]time.Duration, len(methods))
+		for i, m := range methods {
+			start := time.Now()
+			for r := 0; r < reps; r++ {
+				m.multiply(x, y)
+			}
+			times[i] = time.Since(start)
+		}
+		// the crossover is where a method gets ahead and stays there
+		for i := 1; i < len(methods); i++ {
+			switch {
+			case times[i] >= times[0]:
+				crossover[i] = 0
+			case crossover[i] == 0:
+				crossover[i] = n
+			}
+		}
+	}
+	for i := 1; i < len(methods); i++ {
+		if crossover[i] == 0 {
+			fmt.Fprintf(os.Stderr, "polynomial %s isn't ahead of schoolbook at %d\n", methods[i].name, size)
+		} else {
+			fmt.Fprintf(os.Stderr, "polynomial %s beats schoolbook from n = %d\n", methods[i].name, crossover[i])
+		}
+	}
+
+	return totalTime
+}
+
 func heapify(arr []int, n, i int) {
 	largest := i
 	left := 2*i + 1
@@ -405,13 +2227,23 @@ func dataStructures(size int) float64 {
 }
 
 func main() {
+	matmulWorkers := flag.Int("matmul-workers", runtime.NumCPU(), "goroutines for the parallel matrix multiply")
+	matrixSize := flag.Int("matrix-size", 0, "matrix size for the matrix operations, 0 uses 40 x scale factor")
+	blockSize := flag.Int("block", 32, "block size for the blocked matrix multiply")
+	autoTune := flag.Bool("block-autotune", false, "probe block sizes first and use the fastest, overrides -block")
+	verify := flag.Bool("verify", false, "also report the accuracy of the estimates")
+	gridSize := flag.Int("grid-size", 0, "grid size for the 2d fft blur run with -extended, 0 uses 64 x scale factor, rounded up to a power of two")
+	fftWorkers := flag.Int("fft-workers", runtime.NumCPU(), "goroutines for the parallel 2d fft run with -extended")
+	extended := flag.Bool("extended", false, "also run the go-only workloads, reported on stderr and kept out of the total")
+	flag.Parse()
+
 	scaleFactor := 1
-	
-	if len(os.Args) > 1 {
+
+	if flag.NArg() > 0 {
 		var err error
-		scaleFactor, err = strconv.Atoi(os.Args[1])
+		scaleFactor, err = strconv.Atoi(flag.Arg(0))
 		if err != nil {
-			fmt.Println("Invalid scale factor:", os.Args[1])
+			fmt.Println("Invalid scale factor:", flag.Arg(0))
 			os.Exit(1)
 		}
 		if scaleFactor < 1 || scaleFactor > 5 {
@@ -419,14 +2251,45 @@ func main() {
 			os.Exit(1)
 		}
 	}
-	
+
+	size := 40 * scaleFactor
+	if *matrixSize > 0 {
+		size = *matrixSize
+	}
+	block := max(*blockSize, 1)
+	if *autoTune {
+		// the probe is a setup step, it isn't part of the total
+		block = autoTuneBlock(size, []int{8, 16, 32, 64, 128})
+	}
+
 	totalTime := 0.0
-	
-	totalTime += matrixOperations(40 * scaleFactor)
+
+	totalTime += matrixOperations(size, block, max(*matmulWorkers, 1))
 	totalTime += numberTheory(80000 * scaleFactor)
-	totalTime += statisticalComputing(300000 * scaleFactor)
+	totalTime += statisticalComputing(300000*scaleFactor, *verify)
 	totalTime += signalProcessing(256 * scaleFactor)
 	totalTime += dataStructures(30000 * scaleFactor)
-	
+
+	// workloads the other languages don't run, the total stays comparable
+	if *extended {
+		strassenTest(96*scaleFactor, block)
+		linearSolver(100 * scaleFactor)
+		primalityPhases(80000 * scaleFactor)
+		segmentedSievePhase(80000 * scaleFactor)
+		modularArithmetic(80000 * scaleFactor)
+		mcmcSampling(100000 * scaleFactor)
+		grid := 64 * scaleFactor
+		if *gridSize > 0 {
+			grid = *gridSize
+		}
+		imageConvolution(grid, max(*fftWorkers, 1))
+		polynomialMultiplication(2000 * scaleFactor)
+		rngComparison(1000000 * scaleFactor)
+	}
+
+	if libraryComparison != nil {
+		libraryComparison(scaleFactor, size, block)
+	}
+
 	fmt.Printf("%.3f\n", totalTime)
-}
\ No newline at end of file
+}
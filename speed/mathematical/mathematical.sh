@@ -113,10 +113,23 @@ if [ $test_exit_code -ne 0 ]; then
     exit 1; 
 fi
 
-# i think Go is pretty straightforward, 'go build' should do everything
 echo "Compiling Go code..."
-go build -ldflags="-s -w" -gcflags="-B" -o "mathematical_go${EXE_EXT}" mathematical.go
-if [ $? -ne 0 ]; then echo "Go compilation failed. Stopping."; exit 1; fi
+# builds as a module so tagged files get picked up, the gonum comparison
+# (mat, fourier, stat) is an opt-in build tag
+# e.g. GO_BUILD_TAGS="gonum" ./mathematical.sh
+# go won't build a directory that also holds .c files, so the go sources
+# build from a copy of their own
+rm -rf go_build && mkdir go_build && cp *.go go_build/
+cd go_build
+go mod init mathematical_bench > /dev/null 2>&1
+if [ -n "$GO_BUILD_TAGS" ]; then
+    echo "Building Go with tags: $GO_BUILD_TAGS"
+    go mod tidy > /dev/null 2>&1
+fi
+go build -tags "$GO_BUILD_TAGS" -ldflags="-s -w" -gcflags="-B" -o "../mathematical_go${EXE_EXT}" .
+go_status=$?
+cd ..
+if [ $go_status -ne 0 ]; then echo "Go compilation failed. Stopping."; exit 1; fi
 
 # julia doesn't need compilation, it's JIT compiled
 echo "Julia ready (JIT compiled at runtime)"
@@ -216,5 +229,6 @@ if [ "$IS_WINDOWS" = true ]; then
 else
     rm -f mathematical_c mathematical_cpp mathematical_go mathematical_nim mathematical_rust mathematical*.class
 fi
+rm -rf go_build
 
 echo "All done! Thanks for running this comprehensive mathematical benchmark!"
\ No newline at end of file
@@ -0,0 +1,105 @@
+//go:build gonum
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"os"
+	"time"
+
+	"gonum.org/v1/gonum/dsp/fourier"
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
+)
+
+// gonum's mat, fourier and stat against the hand-written kernels
+func init() {
+	libraryComparison = gonumComparison
+}
+
+func reportComparison(name string, hand, library time.Duration, maxDiff float64) {
+	fmt.Fprintf(os.Stderr, "gonum %-28s hand-written %9.3f ms, gonum %9.3f ms (%.2fx), max diff %.1e\n",
+		name, float64(hand.Nanoseconds())/1000000.0, float64(library.Nanoseconds())/1000000.0,
+		float64(hand)/float64(library), maxDiff)
+}
+
+func gonumComparison(scaleFactor, size, block int) {
+	rand.Seed(42)
+
+	// matrix multiply, at the size matrixOperations measured
+	a, b := newMatrix[float64](size), newMatrix[float64](size)
+	aData, bData := make([]float64, size*size), make([]float64, size*size)
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
+			a[i][j] = rand.Float64()*9 + 1
+			b[i][j] = rand.Float64()*9 + 1
+			aData[i*size+j] = a[i][j]
+			bData[i*size+j] = b[i][j]
+		}
+	}
+	start := time.Now()
+	c := newMatrix[float64](size)
+	blockedMultiply(a, b, c, size, block, 0, size)
+	hand := time.Since(start)
+
+	start = time.Now()
+	var product mat.Dense
+	product.Mul(mat.NewDense(size, size, aData), mat.NewDense(size, size, bData))
+	library := time.Since(start)
+
+	maxDiff := 0.0
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
+			maxDiff = math.Max(maxDiff, math.Abs(product.At(i, j)-c[i][j])/c[i][j])
+		}
+	}
+	reportComparison(fmt.Sprintf("matmul %dx%d", size, size), hand, library, maxDiff)
+
+	// forward fft, repeated since one transform is too quick to time
+	n := nextPowerOfTwo(256 * scaleFactor)
+	const repeats = 200
+	signal := make([]complex128, n)
+	for i := range signal {
+		signal[i] = complex(rand.Float64()*2-1, rand.Float64()*2-1)
+	}
+	ours := make([]complex128, n)
+	start = time.Now()
+	for r := 0; r < repeats; r++ {
+		copy(ours, signal)
+		fft(ours)
+	}
+	hand = time.Since(start)
+
+	transform := fourier.NewCmplxFFT(n)
+	theirs := make([]complex128, n)
+	start = time.Now()
+	for r := 0; r < repeats; r++ {
+		transform.Coefficients(theirs, signal)
+	}
+	library = time.Since(start)
+
+	maxDiff = 0.0
+	for i := range ours {
+		maxDiff = math.Max(maxDiff, cmplx.Abs(ours[i]-theirs[i]))
+	}
+	reportComparison(fmt.Sprintf("fft %d x%d", n, repeats), hand, library, maxDiff)
+
+	// mean and population variance of normal samples
+	values := make([]float64, 150000*scaleFactor)
+	for i := range values {
+		values[i] = rand.NormFloat64()
+	}
+	start = time.Now()
+	mean, variance := meanVariance(values)
+	hand = time.Since(start)
+
+	start = time.Now()
+	libMean, libVariance := stat.PopMeanVariance(values, nil)
+	library = time.Since(start)
+
+	reportComparison(fmt.Sprintf("mean/variance %d", len(values)), hand, library,
+		math.Max(math.Abs(mean-libMean), math.Abs(variance-libVariance)))
+}
@@ -1,45 +1,223 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"math"
 	"math/rand"
 	"os"
+	"os/exec"
 	"runtime"
+	"runtime/debug"
+	"runtime/metrics"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 	"unsafe"
 )
 
-// simple arena allocator
+// arena allocator: bump allocation out of chunks, when a chunk fills up a new
+// one is chained on, and everything goes away at once with Reset
+// the chunks are []byte so the gc never scans them, types handed out by
+// Alloc must not contain pointers
 type Arena struct {
-	buffer []byte
-	used   int
+	buffer    []byte
+	used      int
+	chunks    [][]byte // filled chunks, kept alive until Reset
+	chunkSize int
+	align     int
+	free      map[arenaBlock][]unsafe.Pointer // nil unless the free list is on
+}
+
+// arenaBlock is a free list bucket, blocks are only reused for the same
+// size and alignment
+type arenaBlock struct {
+	size, align uintptr
+}
+
+// ArenaOptions configures NewArenaWithOptions, zero values take the defaults
+type ArenaOptions struct {
+	ChunkSize int  // bytes per chunk, default 64KB
+	Alignment int  // minimum alignment, a power of two, default 8
+	FreeList  bool // let Free hand blocks back for reuse
 }
 
 func NewArena(size int) *Arena {
-	return &Arena{
-		buffer: make([]byte, size),
-		used:   0,
+	return NewArenaWithOptions(ArenaOptions{ChunkSize: size})
+}
+
+func NewArenaWithOptions(opts ArenaOptions) *Arena {
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = 64 * 1024
+	}
+	if opts.Alignment <= 0 {
+		opts.Alignment = 8
 	}
+	if opts.Alignment&(opts.Alignment-1) != 0 {
+		panic("arena alignment must be a power of two")
+	}
+
+	a := &Arena{
+		buffer:    make([]byte, opts.ChunkSize),
+		chunkSize: opts.ChunkSize,
+		align:     opts.Alignment,
+	}
+	if opts.FreeList {
+		a.free = make(map[arenaBlock][]unsafe.Pointer)
+	}
+	return a
 }
 
 func (a *Arena) Allocate(size int) unsafe.Pointer {
-	// align to 8 bytes
-	size = (size + 7) &^ 7
-	
-	if a.used+size > len(a.buffer) {
-		return nil
+	return a.allocate(size, a.align)
+}
+
+func (a *Arena) allocate(size int, align int) unsafe.Pointer {
+	// round the size up too so back-to-back blocks stay aligned
+	size = max((size+align-1)&^(align-1), align)
+
+	offset := a.alignedOffset(align)
+	if offset+size > len(a.buffer) {
+		// chain a new chunk, big enough for oversized requests
+		a.chunks = append(a.chunks, a.buffer)
+		a.buffer = make([]byte, max(a.chunkSize, size+align))
+		a.used = 0
+		offset = a.alignedOffset(align)
 	}
-	
-	ptr := unsafe.Pointer(&a.buffer[a.used])
-	a.used += size
+
+	ptr := unsafe.Pointer(&a.buffer[offset])
+	a.used = offset + size
 	return ptr
 }
 
+// alignedOffset is the next offset in the current chunk whose address is a
+// multiple of align
+func (a *Arena) alignedOffset(align int) int {
+	base := uintptr(unsafe.Pointer(unsafe.SliceData(a.buffer)))
+	addr := (base + uintptr(a.used) + uintptr(align-1)) &^ uintptr(align-1)
+	return int(addr - base)
+}
+
+// Chunks is how many chunks the arena holds right now
+func (a *Arena) Chunks() int {
+	return len(a.chunks) + 1
+}
+
+// Reset releases every allocation, the newest chunk is kept for reuse
 func (a *Arena) Reset() {
 	a.used = 0
+	a.chunks = nil
+	clear(a.free)
+}
+
+// Alloc returns a zeroed *T carved out of the arena, aligned to the larger
+// of the arena's and T's alignment, T must not contain pointers
+func Alloc[T any](a *Arena) *T {
+	var zero T
+	block := arenaBlock{unsafe.Sizeof(zero), uintptr(max(a.align, int(unsafe.Alignof(zero))))}
+
+	var p *T
+	if blocks := a.free[block]; len(blocks) > 0 {
+		p = (*T)(blocks[len(blocks)-1])
+		a.free[block] = blocks[:len(blocks)-1]
+	} else {
+		p = (*T)(a.allocate(int(block.size), int(block.align)))
+	}
+	// chunks are reused after Reset, so clear whatever was there
+	*p = zero
+	return p
+}
+
+// Free hands p back to the arena so the next Alloc of the same type can
+// reuse it, without a free list it's a no-op and memory waits for Reset
+func Free[T any](a *Arena, p *T) {
+	if a.free == nil || p == nil {
+		return
+	}
+	var zero T
+	block := arenaBlock{unsafe.Sizeof(zero), uintptr(max(a.align, int(unsafe.Alignof(zero))))}
+	a.free[block] = append(a.free[block], unsafe.Pointer(p))
+}
+
+// ShardedArena is a concurrency-safe arena, goroutines allocate from their
+// own shard with an atomic bump pointer so the fast path never locks, and
+// the lock is only taken to chain a new chunk when one fills up
+type ShardedArena struct {
+	shards    []ArenaShard
+	chunkSize int
+}
+
+// ArenaShard is one shard, goroutines sharing a shard is safe but contended
+type ArenaShard struct {
+	current atomic.Pointer[arenaChunk]
+	mu      sync.Mutex
+	chunks  []*arenaChunk // filled chunks, kept alive until Reset
+	size    int
+	_       [64]byte // keep neighbouring shards off each other's cache lines
+}
+
+type arenaChunk struct {
+	buffer []byte
+	used   atomic.Int64
+}
+
+func NewShardedArena(shards int, chunkSize int) *ShardedArena {
+	a := &ShardedArena{shards: make([]ArenaShard, max(shards, 1)), chunkSize: chunkSize}
+	for i := range a.shards {
+		a.shards[i].size = chunkSize
+		a.shards[i].current.Store(&arenaChunk{buffer: make([]byte, chunkSize)})
+	}
+	return a
+}
+
+// Shard picks the shard for a goroutine, pass something stable per
+// goroutine like a worker id
+func (a *ShardedArena) Shard(hint int) *ArenaShard {
+	return &a.shards[hint%len(a.shards)]
+}
+
+// Allocate returns size bytes aligned to 8
+func (s *ArenaShard) Allocate(size int) unsafe.Pointer {
+	size = (size + 7) &^ 7
+	for {
+		chunk := s.current.Load()
+		end := chunk.used.Add(int64(size))
+		if end <= int64(len(chunk.buffer)) {
+			return unsafe.Pointer(&chunk.buffer[end-int64(size)])
+		}
+
+		// full, whoever gets the lock first chains the next chunk and the
+		// rest retry on it
+		s.mu.Lock()
+		if s.current.Load() == chunk {
+			s.chunks = append(s.chunks, chunk)
+			s.current.Store(&arenaChunk{buffer: make([]byte, max(s.size, size))})
+		}
+		s.mu.Unlock()
+	}
+}
+
+// Reset releases everything in every shard, no allocation may be in flight
+func (a *ShardedArena) Reset() {
+	for i := range a.shards {
+		s := &a.shards[i]
+		s.chunks = nil
+		s.current.Load().used.Store(0)
+	}
+}
+
+// AllocShared is Alloc for a shard, T must not contain pointers and can't
+// need more than 8 byte alignment
+func AllocShared[T any](s *ArenaShard) *T {
+	var zero T
+	if unsafe.Alignof(zero) > 8 {
+		panic("sharded arena only aligns to 8 bytes")
+	}
+	p := (*T)(s.Allocate(int(unsafe.Sizeof(zero))))
+	*p = zero
+	return p
 }
 
 // allocation patterns test - sequential, random, producer-consumer
@@ -87,6 +265,83 @@ func allocationPatternsTest(iterations int) float64 {
 	return float64(duration.Nanoseconds()) / 1000000.0
 }
 
+// fragmentationTest repeats the allocation patterns with a heap snapshot
+// after each phase, then frees every other block of the random pattern so
+// the survivors pin their spans and leave holes
+func fragmentationTest(iterations int) float64 {
+	heap := []heapSnapshot{readHeapSnapshot("start")}
+	// the snapshots stop the world, their time is taken back out
+	var paused time.Duration
+	snapshot := func(phase string) {
+		t := time.Now()
+		heap = append(heap, readHeapSnapshot(phase))
+		paused += time.Since(t)
+	}
+	start := time.Now()
+
+	ptrs := make([][]byte, iterations)
+	for i := 0; i < iterations; i++ {
+		ptrs[i] = make([]byte, 64+(i%256))
+	}
+	snapshot("sequential allocated")
+	ptrs = nil
+	runtime.GC()
+	snapshot("sequential freed")
+
+	rand.Seed(42)
+	rawPtrs := make([]unsafe.Pointer, iterations)
+	for i := range rawPtrs {
+		ptr := make([]byte, 32+rand.Intn(512))
+		rawPtrs[i] = unsafe.Pointer(&ptr[0])
+	}
+	snapshot("random allocated")
+	for i := 1; i < len(rawPtrs); i += 2 {
+		rawPtrs[i] = nil
+	}
+	runtime.GC()
+	snapshot("random half freed")
+	runtime.KeepAlive(rawPtrs)
+	rawPtrs = nil
+	runtime.GC()
+	snapshot("random freed")
+	duration := time.Since(start) - paused
+
+	fmt.Fprintf(os.Stderr, "fragmentation heap: %-22s %10s %10s %10s %10s %8s\n", "phase", "inuse MB", "idle MB", "released", "live MB", "frag")
+	for _, h := range heap {
+		fmt.Fprintf(os.Stderr, "fragmentation heap: %-22s %10.2f %10.2f %10.2f %10.2f %7.1f%%\n", h.phase,
+			mb(h.inuse), mb(h.idle), mb(h.released), mb(h.alloc), h.fragmentation()*100)
+	}
+	return float64(duration.Nanoseconds()) / 1000000.0
+}
+
+// heapSnapshot is the heap span accounting from MemStats at one phase
+type heapSnapshot struct {
+	phase    string
+	inuse    uint64
+	idle     uint64
+	released uint64
+	alloc    uint64
+}
+
+func readHeapSnapshot(phase string) heapSnapshot {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return heapSnapshot{phase, m.HeapInuse, m.HeapIdle, m.HeapReleased, m.HeapAlloc}
+}
+
+// fragmentation estimates how much of the in-use spans isn't holding
+// objects, freed slots the allocator can only reuse for the same size class
+func (h heapSnapshot) fragmentation() float64 {
+	if h.inuse == 0 {
+		return 0
+	}
+	return 1 - float64(h.alloc)/float64(h.inuse)
+}
+
+func mb(bytes uint64) float64 {
+	return float64(bytes) / (1024 * 1024)
+}
+
 // worker function for gc stress test
 func gcStressWorker(threadID int, iterations int, counter *int64, wg *sync.WaitGroup) {
 	defer wg.Done()
@@ -186,6 +441,50 @@ func cacheLocalityTest(iterations int) float64 {
 	return float64(duration.Nanoseconds()) / 1000000.0
 }
 
+// working-set sweep does the same number of random cache-line reads over
+// growing slices of one buffer, doubling from 32KB, so the throughput drops
+// at each cache level (L1, L2, L3) and once more at DRAM
+func workingSetSweep(maxBytes int, accesses int) float64 {
+	const line = 64
+	words := make([]uint64, maxBytes/8)
+	// fault every page in up front so the sweep only measures reads
+	for i := 0; i < len(words); i += 4096 / 8 {
+		words[i] = uint64(i)
+	}
+
+	fmt.Fprintf(os.Stderr, "%-12s %12s %12s\n", "working set", "MB/s", "ns/access")
+	totalTime := 0.0
+	var sum uint64
+	for size := 32 * 1024; size <= maxBytes; size *= 2 {
+		// sizes are powers of two, so masking picks a random line
+		mask := uint64(size/line - 1)
+		x := uint64(88172645463325252)
+
+		start := time.Now()
+		for i := 0; i < accesses; i++ {
+			// xorshift keeps index generation cheap next to the load, and
+			// folding in the previous value makes each load wait for the last
+			// one, otherwise overlapping misses hide the latency
+			x ^= x << 13
+			x ^= x >> 7
+			x ^= x << 17
+			sum += words[((x^sum)&mask)*(line/8)]
+		}
+		duration := time.Since(start)
+
+		ms := float64(duration.Nanoseconds()) / 1000000.0
+		totalTime += ms
+		label := fmt.Sprintf("%dKB", size/1024)
+		if size >= 1024*1024 {
+			label = fmt.Sprintf("%dMB", size/(1024*1024))
+		}
+		fmt.Fprintf(os.Stderr, "%-12s %12.1f %12.2f\n", label,
+			float64(accesses)*line/(1024*1024)/(ms/1000.0), float64(duration.Nanoseconds())/float64(accesses))
+	}
+	_ = sum // prevent optimization
+	return totalTime
+}
+
 // memory pool performance test
 func memoryPoolTest(iterations int) float64 {
 	start := time.Now()
@@ -275,24 +574,1914 @@ func memoryIntensiveTest(largeSizeMB int) float64 {
 	return float64(duration.Nanoseconds()) / 1000000.0
 }
 
-func main() {
-	scaleFactor := 1
-	
-	if len(os.Args) > 1 {
-		if factor, err := strconv.Atoi(os.Args[1]); err == nil && factor > 0 {
-			scaleFactor = factor
-		} else {
-			fmt.Fprintf(os.Stderr, "Invalid scale factor. Using default 1.\n")
+// typed arena test allocates the same records with make(), the generic
+// arena cache-line aligned in small chunks so chaining kicks in, and the
+// arena with a free list churning through a small live window
+func typedArenaTest(iterations int) float64 {
+	phase := time.Now()
+	elapsed := func() float64 {
+		ms := float64(time.Since(phase).Nanoseconds()) / 1000000.0
+		phase = time.Now()
+		return ms
+	}
+
+	// one make() per record, like the []byte pass in memoryPoolTest
+	heapRecords := make([]*poolRecord, iterations)
+	for i := 0; i < iterations; i++ {
+		r := &make([]poolRecord, 1)[0]
+		r.id = int64(i)
+		r.values[0] = float64(i)
+		heapRecords[i] = r
+	}
+	heapRecords = nil
+	makeTime := elapsed()
+
+	typed := NewArenaWithOptions(ArenaOptions{ChunkSize: 64 * 1024, Alignment: 64})
+	arenaRecords := make([]*poolRecord, iterations)
+	for i := 0; i < iterations; i++ {
+		r := Alloc[poolRecord](typed)
+		r.id = int64(i)
+		r.values[0] = float64(i)
+		arenaRecords[i] = r
+	}
+	typedChunks := typed.Chunks()
+	arenaRecords = nil
+	typed.Reset()
+	arenaTime := elapsed()
+
+	// only a small window is live at a time, freed records are reused
+	recycled := NewArenaWithOptions(ArenaOptions{ChunkSize: 64 * 1024, FreeList: true})
+	var window [64]*poolRecord
+	for i := 0; i < iterations; i++ {
+		Free(recycled, window[i%len(window)])
+		r := Alloc[poolRecord](recycled)
+		r.id = int64(i)
+		r.values[0] = float64(i)
+		window[i%len(window)] = r
+	}
+	freeListTime := elapsed()
+
+	fmt.Fprintf(os.Stderr, "typed arena: make %.3f ms, arena %.3f ms (%d chunks), arena+free list %.3f ms (%d chunks)\n",
+		makeTime, arenaTime, typedChunks, freeListTime, recycled.Chunks())
+
+	// same typed workload on the runtime arena, only on GOEXPERIMENT=arenas
+	// builds, reported next to ours but not timed into the result
+	if runtimeArenaRecords != nil {
+		elapsed()
+		runtimeArenaRecords(iterations)
+		fmt.Fprintf(os.Stderr, "typed arena on the runtime arena: %.3f ms (homegrown arena %.3f ms)\n", elapsed(), arenaTime)
+	}
+
+	return makeTime + arenaTime + freeListTime
+}
+
+// poolRecord is a pointer-free 128-byte record for the typed arena
+type poolRecord struct {
+	id     int64
+	values [15]float64
+}
+
+// parallel memory pool test has every goroutine allocate its own batch of
+// poolRecords at once, from make, through a sync.Pool, and from a sharded
+// arena with one shard per goroutine
+func memoryPoolParallelTest(numGoroutines int, iterations int) float64 {
+	pool := sync.Pool{New: func() any { return new(poolRecord) }}
+	arena := NewShardedArena(numGoroutines, 64*1024)
+
+	variants := []struct {
+		name    string
+		alloc   func(shard *ArenaShard) *poolRecord
+		release func(r *poolRecord) // nil when the batch is just dropped
+	}{
+		{"memory pool parallel make", func(*ArenaShard) *poolRecord { return new(poolRecord) }, nil},
+		{"memory pool parallel sync.Pool", func(*ArenaShard) *poolRecord { return pool.Get().(*poolRecord) },
+			func(r *poolRecord) { pool.Put(r) }},
+		{"memory pool parallel sharded arena", AllocShared[poolRecord], nil},
+	}
+
+	// records go out in batches of 256, get filled, then the batch is dropped
+	// or handed back, the arena is reset once at the end
+	const batch = 256
+	totalTime := 0.0
+	for _, v := range variants {
+		totalTime += measure(v.name, func() float64 {
+			start := time.Now()
+			var wg sync.WaitGroup
+			for g := 0; g < numGoroutines; g++ {
+				wg.Add(1)
+				go func(id int) {
+					defer wg.Done()
+					shard := arena.Shard(id)
+					records := make([]*poolRecord, batch)
+					for done := 0; done < iterations; done += batch {
+						for i := range records {
+							r := v.alloc(shard)
+							r.id = int64(done + i)
+							r.values[0] = float64(id)
+							records[i] = r
+						}
+						if v.release != nil {
+							for _, r := range records {
+								v.release(r)
+							}
+						}
+					}
+				}(g)
+			}
+			wg.Wait()
+			arena.Reset()
+			duration := time.Since(start)
+			return float64(duration.Nanoseconds()) / 1000000.0
+		})
+		r := results[len(results)-1]
+		ops := float64(numGoroutines * iterations)
+		fmt.Fprintf(os.Stderr, "%s: %d goroutines, %.1f M records/s, %.3f allocs/record\n", v.name, numGoroutines,
+			ops/(r.Millis*1000.0), float64(r.GC.Objects)/ops)
+	}
+	return totalTime
+}
+
+// runtimeArenaRecords allocates iterations poolRecords from the runtime's
+// arena package and frees it, set by memory_arenas.go when the experiment is on
+var runtimeArenaRecords func(iterations int)
+
+// streamKernel is one STREAM loop over the index range [lo, hi), bytes is
+// the traffic per element it's credited with
+type streamKernel struct {
+	name  string
+	bytes int
+	run   func(a, b, c []float64, scalar float64, lo, hi int)
+}
+
+var streamKernels = []streamKernel{
+	{"copy", 16, func(a, b, c []float64, scalar float64, lo, hi int) {
+		for i := lo; i < hi; i++ {
+			c[i] = a[i]
 		}
+	}},
+	{"scale", 16, func(a, b, c []float64, scalar float64, lo, hi int) {
+		for i := lo; i < hi; i++ {
+			b[i] = scalar * c[i]
+		}
+	}},
+	{"add", 24, func(a, b, c []float64, scalar float64, lo, hi int) {
+		for i := lo; i < hi; i++ {
+			c[i] = a[i] + b[i]
+		}
+	}},
+	{"triad", 24, func(a, b, c []float64, scalar float64, lo, hi int) {
+		for i := lo; i < hi; i++ {
+			a[i] = b[i] + scalar*c[i]
+		}
+	}},
+}
+
+// parallelRange splits [0, n) into one contiguous chunk per goroutine
+func parallelRange(n int, threads int, body func(lo, hi int)) {
+	var wg sync.WaitGroup
+	chunk := (n + threads - 1) / threads
+	for lo := 0; lo < n; lo += chunk {
+		wg.Add(1)
+		go func(lo, hi int) {
+			defer wg.Done()
+			body(lo, hi)
+		}(lo, min(lo+chunk, n))
 	}
-	
+	wg.Wait()
+}
+
+// stream bandwidth test runs the STREAM copy/scale/add/triad kernels over
+// three large arrays with 1..maxThreads goroutines, keeping the best of a
+// few passes per kernel like STREAM does
+func streamBandwidthTest(largeSizeMB int, maxThreads int) float64 {
+	n := largeSizeMB * 1024 * 1024 / 8
+	a := make([]float64, n)
+	b := make([]float64, n)
+	c := make([]float64, n)
+
+	// first touch in parallel so pages land near the threads using them
+	parallelRange(n, maxThreads, func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			a[i] = 1.0
+			b[i] = 2.0
+			c[i] = 0.0
+		}
+	})
+
+	threadCounts := []int{}
+	for t := 1; t < maxThreads; t *= 2 {
+		threadCounts = append(threadCounts, t)
+	}
+	threadCounts = append(threadCounts, maxThreads)
+
+	const passes = 3
+	const scalar = 3.0
 	totalTime := 0.0
-	
-	totalTime += allocationPatternsTest(10000 * scaleFactor)
-	totalTime += gcStressTest(4, 2500*scaleFactor)
-	totalTime += cacheLocalityTest(5000 * scaleFactor)
-	totalTime += memoryPoolTest(8000 * scaleFactor)
-	totalTime += memoryIntensiveTest(100 * scaleFactor)
-	
+	fmt.Fprintf(os.Stderr, "%-8s %8s %10s\n", "kernel", "threads", "GB/s")
+	for _, threads := range threadCounts {
+		for _, kernel := range streamKernels {
+			best := math.MaxFloat64
+			for pass := 0; pass < passes; pass++ {
+				start := time.Now()
+				parallelRange(n, threads, func(lo, hi int) {
+					kernel.run(a, b, c, scalar, lo, hi)
+				})
+				ms := float64(time.Since(start).Nanoseconds()) / 1000000.0
+				totalTime += ms
+				best = min(best, ms)
+			}
+			gbPerSec := float64(kernel.bytes) * float64(n) / 1e9 / (best / 1000.0)
+			fmt.Fprintf(os.Stderr, "%-8s %8d %10.2f\n", kernel.name, threads, gbPerSec)
+		}
+	}
+
+	_ = a[n/2] + b[n/2] + c[n/2] // prevent optimization
+	return totalTime
+}
+
+// sync.Pool test hands out buffers of each size to several goroutines, once
+// with a fresh make per operation and once recycled through a sync.Pool
+func syncPoolTest(numGoroutines int, opsPerGoroutine int, sizes []int) float64 {
+	totalTime := 0.0
+	for _, size := range sizes {
+		// the pool holds pointers so Put itself doesn't allocate
+		pool := sync.Pool{
+			New: func() any {
+				buf := make([]byte, size)
+				return &buf
+			},
+		}
+
+		for _, pooled := range []bool{false, true} {
+			mode := "make"
+			if pooled {
+				mode = "pool"
+			}
+			name := fmt.Sprintf("sync.Pool %s %dB", mode, size)
+
+			totalTime += measure(name, func() float64 {
+				start := time.Now()
+
+				var wg sync.WaitGroup
+				var checksum int64
+				for g := 0; g < numGoroutines; g++ {
+					wg.Add(1)
+					go func(id int) {
+						defer wg.Done()
+						var sum int64
+						for i := 0; i < opsPerGoroutine; i++ {
+							var buf []byte
+							var ref *[]byte
+							if pooled {
+								ref = pool.Get().(*[]byte)
+								buf = *ref
+							} else {
+								buf = make([]byte, size)
+							}
+
+							// touch one byte per cache line, like filling it would
+							for j := 0; j < len(buf); j += 64 {
+								buf[j] = byte(i + id)
+							}
+							sum += int64(buf[len(buf)-1])
+
+							if pooled {
+								pool.Put(ref)
+							}
+						}
+						atomic.AddInt64(&checksum, sum)
+					}(g)
+				}
+				wg.Wait()
+				_ = atomic.LoadInt64(&checksum) // prevent optimization
+
+				duration := time.Since(start)
+				return float64(duration.Nanoseconds()) / 1000000.0
+			})
+
+			r := results[len(results)-1]
+			ops := float64(numGoroutines * opsPerGoroutine)
+			fmt.Fprintf(os.Stderr, "%s: %.0f ops/s, %.3f allocs/op\n", name, ops/(r.Millis/1000.0), float64(r.GC.Objects)/ops)
+		}
+	}
+	return totalTime
+}
+
+// paddedCounter fills two cache lines, the adjacent-line prefetcher on x86
+// pulls lines in pairs so 64 bytes alone can still share
+type paddedCounter struct {
+	value int64
+	_     [120]byte
+}
+
+// false sharing test has goroutines increment their own counter, first with
+// all counters packed into one cache line and then padded apart, the line
+// bouncing between cores is the whole difference
+func falseSharingTest(numGoroutines int, incrementsPerGoroutine int) float64 {
+	// eight int64s fill one 64-byte line
+	numGoroutines = min(numGoroutines, 8)
+
+	run := func(counter func(id int) *int64) float64 {
+		start := time.Now()
+		var wg sync.WaitGroup
+		for g := 0; g < numGoroutines; g++ {
+			wg.Add(1)
+			go func(c *int64) {
+				defer wg.Done()
+				// atomics keep every increment going through memory
+				for i := 0; i < incrementsPerGoroutine; i++ {
+					atomic.AddInt64(c, 1)
+				}
+			}(counter(g))
+		}
+		wg.Wait()
+		duration := time.Since(start)
+		return float64(duration.Nanoseconds()) / 1000000.0
+	}
+
+	var packed [8]int64
+	padded := make([]paddedCounter, numGoroutines)
+
+	sharedTime := measure("false sharing packed", func() float64 {
+		return run(func(id int) *int64 { return &packed[id] })
+	})
+	paddedTime := measure("false sharing padded", func() float64 {
+		return run(func(id int) *int64 { return &padded[id].value })
+	})
+	_ = packed[0] + padded[0].value // prevent optimization
+
+	if paddedTime > 0 {
+		fmt.Fprintf(os.Stderr, "false sharing: %d goroutines, packed counters %.2fx slower than padded\n",
+			numGoroutines, sharedTime/paddedTime)
+	}
+	return sharedTime + paddedTime
+}
+
+// pageMapper maps anonymous memory with one page configuration, platforms
+// that support it fill hugePageMappers from memory_<os>.go
+type pageMapper struct {
+	name  string
+	mmap  func(size int) ([]byte, error)
+	unmap func(mem []byte) error
+}
+
+var hugePageMappers []pageMapper
+
+// minorFaults returns the process' minor page faults so far, -1 if unknown
+var minorFaults = func() int64 { return -1 }
+
+// huge page test maps the same region with each page configuration, faults
+// it in one byte per 4KB and then reads random cache lines from it; larger
+// pages mean fewer faults and fewer tlb misses
+func hugePageTest(sizeMB int, accesses int) float64 {
+	if len(hugePageMappers) == 0 {
+		fmt.Fprintf(os.Stderr, "huge pages: not supported on %s, skipping\n", runtime.GOOS)
+		return 0.0
+	}
+
+	size := sizeMB * 1024 * 1024
+	totalTime := 0.0
+	for _, mapper := range hugePageMappers {
+		faultsBefore := minorFaults()
+		start := time.Now()
+		mem, err := mapper.mmap(size)
+		if err != nil {
+			// hugetlbfs needs pages reserved in /proc/sys/vm/nr_hugepages
+			fmt.Fprintf(os.Stderr, "huge pages %s: could not map %dMB, skipping -> %v\n", mapper.name, sizeMB, err)
+			continue
+		}
+		for i := 0; i < size; i += 4096 {
+			mem[i] = byte(i)
+		}
+		faultTime := float64(time.Since(start).Nanoseconds()) / 1000000.0
+		faults := minorFaults() - faultsBefore
+
+		// dependent random reads, same loop as the working-set sweep
+		mask := uint64(size/64 - 1)
+		x := uint64(88172645463325252)
+		var sum uint64
+		start = time.Now()
+		for i := 0; i < accesses; i++ {
+			x ^= x << 13
+			x ^= x >> 7
+			x ^= x << 17
+			sum += uint64(mem[((x^sum)&mask)*64])
+		}
+		accessTime := float64(time.Since(start).Nanoseconds()) / 1000000.0
+		_ = sum // prevent optimization
+
+		mapper.unmap(mem)
+		totalTime += faultTime + accessTime
+
+		faultInfo := "faults n/a"
+		if faultsBefore >= 0 && faults > 0 {
+			faultInfo = fmt.Sprintf("%d faults, %.0f ns/fault", faults, faultTime*1000000.0/float64(faults))
+		}
+		fmt.Fprintf(os.Stderr, "huge pages %-10s fault-in %8.2f ms (%s), random access %8.2f ms (%.1f ns/access)\n",
+			mapper.name, faultTime, faultInfo, accessTime, accessTime*1000000.0/float64(accesses))
+	}
+	return totalTime
+}
+
+// tlb stress test does dependent random reads of one line per stride over a
+// multi-GB mapping, at page-sized and larger strides every read lands on its
+// own page, a control run reads the same number of lines packed densely so
+// the difference is the tlb miss cost rather than the cache miss cost
+func tlbStressTest(sizeMB int, accesses int, compareHuge bool) float64 {
+	size := sizeMB * 1024 * 1024
+	strides := []int{4 * 1024, 16 * 1024, 64 * 1024, 2 * 1024 * 1024}
+
+	// sizes and strides are powers of two so the line count works as a mask
+	readLines := func(mem []byte, stride int, lines int) float64 {
+		mask := uint64(lines - 1)
+		x := uint64(88172645463325252)
+		var sum uint64
+		start := time.Now()
+		for i := 0; i < accesses; i++ {
+			x ^= x << 13
+			x ^= x >> 7
+			x ^= x << 17
+			line := (x ^ sum) & mask
+			// vary the line within the page so they don't share cache sets
+			offset := uint64(0)
+			if stride > 64 {
+				offset = (line * 64) & uint64(stride-1)
+			}
+			sum += uint64(mem[line*uint64(stride)+offset])
+		}
+		_ = sum // prevent optimization
+		return float64(time.Since(start).Nanoseconds()) / 1000000.0
+	}
+
+	// plain 4KB pages, then transparent huge pages if asked for
+	mappers := []pageMapper{anonMapper}
+	for _, m := range hugePageMappers {
+		if m.name == "4KB" {
+			mappers[0] = m
+		}
+		if compareHuge && m.name == "thp" {
+			mappers = append(mappers, m)
+		}
+	}
+	if mappers[0].mmap == nil {
+		mappers[0] = pageMapper{"make", func(size int) ([]byte, error) { return make([]byte, size), nil }, func([]byte) error { return nil }}
+	}
+	if compareHuge && len(mappers) == 1 {
+		fmt.Fprintf(os.Stderr, "tlb stress: huge pages not supported on %s, skipping comparison\n", runtime.GOOS)
+	}
+
+	totalTime := 0.0
+	for _, mapper := range mappers {
+		mem, err := mapper.mmap(size)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "tlb stress %s: could not map %dMB, skipping -> %v\n", mapper.name, sizeMB, err)
+			continue
+		}
+		// fault everything in first, page faults aren't what's measured here
+		for i := 0; i < size; i += 4096 {
+			mem[i] = byte(i)
+		}
+
+		for _, stride := range strides {
+			lines := size / stride
+			name := fmt.Sprintf("tlb stress %s stride %dKB", mapper.name, stride/1024)
+			var control float64
+			totalTime += measure(name, func() float64 {
+				control = readLines(mem, 64, lines)
+				return readLines(mem, stride, lines)
+			})
+			ms := results[len(results)-1].Millis
+			fmt.Fprintf(os.Stderr, "%s: %d lines, %.1f ns/access, dense control %.1f ns/access, tlb cost %.1f ns/access\n",
+				name, lines, ms*1000000.0/float64(accesses), control*1000000.0/float64(accesses),
+				(ms-control)*1000000.0/float64(accesses))
+		}
+		mapper.unmap(mem)
+	}
+	return totalTime
+}
+
+// genObject has a pointer so the collector has to trace the retained set
+type genObject struct {
+	next    *genObject
+	payload [6]int64
+}
+
+// gcCPUSeconds is the cpu time the gc has used so far, marking included,
+// which the pause histogram alone doesn't show
+func gcCPUSeconds() float64 {
+	sample := []metrics.Sample{{Name: "/cpu/classes/gc/total:cpu-seconds"}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindFloat64 {
+		return 0
+	}
+	return sample[0].Value.Float64()
+}
+
+// generational mix test allocates objects where, for each ratio, that many
+// die young for every one that joins an ever-growing retained set, so the
+// live heap and the marking work grow at a rate set by the ratio
+func generationalMixTest(objects int, ratios []int) float64 {
+	totalTime := 0.0
+	for _, ratio := range ratios {
+		name := fmt.Sprintf("generational mix %d:1", ratio)
+		var retained []*genObject
+		var gcCPU float64
+		totalTime += measure(name, func() float64 {
+			// short-lived objects survive briefly in a small ring, like a request's temporaries
+			var young [16]*genObject
+			cpuBefore := gcCPUSeconds()
+			start := time.Now()
+			for i := 0; i < objects; i++ {
+				obj := &genObject{}
+				obj.payload[0] = int64(i)
+				if i%(ratio+1) == ratio {
+					if len(retained) > 0 {
+						obj.next = retained[len(retained)-1]
+					}
+					retained = append(retained, obj)
+					continue
+				}
+				young[i%len(young)] = obj
+			}
+			duration := time.Since(start)
+			gcCPU = (gcCPUSeconds() - cpuBefore) * 1000.0
+			runtime.KeepAlive(young)
+			return float64(duration.Nanoseconds()) / 1000000.0
+		})
+		r := results[len(results)-1]
+		fmt.Fprintf(os.Stderr, "%s: %.1f M allocs/s, %d retained, %d gcs, %.3f ms gc cpu (%.1f%% of run)\n", name,
+			float64(objects)/(r.Millis*1000.0), len(retained), r.GC.Cycles, gcCPU, gcCPU*100/r.Millis)
+		retained = nil
+	}
+	return totalTime
+}
+
+// sizeClasses are the runtime's small object size classes from 16B up,
+// see runtime/sizeclasses.go, anything bigger takes the large object path
+var sizeClasses = []int{
+	16, 24, 32, 48, 64, 80, 96, 112, 128, 144, 160, 176, 192, 208, 224, 240, 256,
+	288, 320, 352, 384, 416, 448, 480, 512, 576, 640, 704, 768, 896, 1024, 1152,
+	1280, 1408, 1536, 1792, 2048, 2304, 2688, 3072, 3200, 3456, 4096, 4864, 5376,
+	6144, 6528, 6784, 6912, 8192, 9472, 9728, 10240, 10880, 12288, 13568, 14336,
+	16384, 18432, 19072, 20480, 21760, 24576, 27264, 28672, 32768,
+}
+
+// sizeClassSink makes every allocation escape, small variable-size makes
+// would otherwise be put on the stack
+var sizeClassSink []byte
+
+// size class test allocates the same number of bytes in every size class,
+// so the table shows how the per-allocation cost scales with object size
+func sizeClassTest(mbPerClass int) float64 {
+	type row struct {
+		size   int
+		count  int
+		millis float64
+	}
+	var rows []row
+
+	totalTime := measure("size classes", func() float64 {
+		total := 0.0
+		for _, size := range sizeClasses {
+			count := mbPerClass * 1024 * 1024 / size
+			start := time.Now()
+			for i := 0; i < count; i++ {
+				buf := make([]byte, size)
+				buf[size-1] = byte(i)
+				sizeClassSink = buf
+			}
+			ms := float64(time.Since(start).Nanoseconds()) / 1000000.0
+			rows = append(rows, row{size, count, ms})
+			total += ms
+		}
+		return total
+	})
+
+	fmt.Fprintf(os.Stderr, "%-10s %10s %12s %10s %10s\n", "size class", "allocs", "M allocs/s", "ns/alloc", "GB/s")
+	for _, r := range rows {
+		fmt.Fprintf(os.Stderr, "%-10d %10d %12.2f %10.1f %10.2f\n", r.size, r.count,
+			float64(r.count)/(r.millis*1000.0), r.millis*1000000.0/float64(r.count),
+			float64(r.count*r.size)/(r.millis/1000.0)/1e9)
+	}
+	return totalTime
+}
+
+// anonMapper maps plain anonymous memory outside the go heap, unix builds
+// set it from memory_unix.go, a nil mmap means unsupported
+var anonMapper pageMapper
+
+// mmap vs make acquires a large buffer from the go heap and straight from
+// the kernel, then times the first touch of every page and the release,
+// for make the release is a gc plus handing the pages back to the os
+func mmapVsMakeTest(sizeMB int, rounds int) float64 {
+	size := sizeMB * 1024 * 1024
+
+	type phases struct{ acquire, touch, release float64 }
+	touch := func(mem []byte) {
+		for i := 0; i < len(mem); i += 4096 {
+			mem[i] = byte(i)
+		}
+	}
+	elapsed := func(start time.Time) float64 {
+		return float64(time.Since(start).Nanoseconds()) / 1000000.0
+	}
+	summarize := func(name string, p phases) {
+		fmt.Fprintf(os.Stderr, "%-5s %dMB x%d: acquire %8.3f ms, first touch %8.3f ms, release %8.3f ms per round\n",
+			name, sizeMB, rounds, p.acquire/float64(rounds), p.touch/float64(rounds), p.release/float64(rounds))
+	}
+
+	var heap phases
+	totalTime := measure("mmap vs make: make", func() float64 {
+		for r := 0; r < rounds; r++ {
+			start := time.Now()
+			buf := make([]byte, size)
+			heap.acquire += elapsed(start)
+
+			start = time.Now()
+			touch(buf)
+			heap.touch += elapsed(start)
+			_ = buf[size-1] // prevent optimization
+
+			start = time.Now()
+			buf = nil
+			debug.FreeOSMemory()
+			heap.release += elapsed(start)
+		}
+		return heap.acquire + heap.touch + heap.release
+	})
+	summarize("make", heap)
+
+	if anonMapper.mmap == nil {
+		fmt.Fprintf(os.Stderr, "mmap: not supported on %s, skipping\n", runtime.GOOS)
+		return totalTime
+	}
+
+	var mapped phases
+	totalTime += measure("mmap vs make: mmap", func() float64 {
+		for r := 0; r < rounds; r++ {
+			start := time.Now()
+			mem, err := anonMapper.mmap(size)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "mmap: could not map %dMB -> %v\n", sizeMB, err)
+				break
+			}
+			mapped.acquire += elapsed(start)
+
+			start = time.Now()
+			touch(mem)
+			mapped.touch += elapsed(start)
+
+			start = time.Now()
+			anonMapper.unmap(mem)
+			mapped.release += elapsed(start)
+		}
+		return mapped.acquire + mapped.touch + mapped.release
+	})
+	summarize("mmap", mapped)
+	return totalTime
+}
+
+// offHeap is a manual allocator over a region outside the go heap, blocks
+// are addressed by offset and freed blocks go on an intrusive free list per
+// size, offset 0 is never handed out so it can mean nil
+type offHeap struct {
+	mem  []byte
+	used uint32
+	free map[uint32]uint32 // size -> offset of the first free block
+}
+
+func (h *offHeap) alloc(size uint32) (uint32, bool) {
+	size = (size + 7) &^ 7
+	if off := h.free[size]; off != 0 {
+		// a free block's first word is the offset of the next one
+		h.free[size] = *(*uint32)(h.at(off))
+		return off, true
+	}
+	if int(h.used+size) > len(h.mem) {
+		return 0, false
+	}
+	off := h.used
+	h.used += size
+	return off, true
+}
+
+func (h *offHeap) release(off, size uint32) {
+	size = (size + 7) &^ 7
+	*(*uint32)(h.at(off)) = h.free[size]
+	h.free[size] = off
+}
+
+func (h *offHeap) at(off uint32) unsafe.Pointer {
+	return unsafe.Pointer(&h.mem[off])
+}
+
+// heapNode and offHeapNode are the same linked list node, the off-heap one
+// links by offset so the gc has nothing to trace
+type heapNode struct {
+	id    int64
+	value float64
+	next  *heapNode
+}
+
+type offHeapNode struct {
+	id    int64
+	value float64
+	next  uint32
+}
+
+// off-heap test builds a linked list, unlinks every third node, refills it
+// and scans it with a collection in between, once with gc-managed nodes and
+// once with nodes in an mmapped region freed by hand
+func offHeapTest(nodes int) float64 {
+	totalTime := measure("off-heap: heap nodes", func() float64 {
+		start := time.Now()
+		var head *heapNode
+		for i := 0; i < nodes; i++ {
+			head = &heapNode{id: int64(i), value: float64(i) * 0.5, next: head}
+		}
+		// unlinked nodes are left for the gc
+		for prev, n, i := (*heapNode)(nil), head, 0; n != nil; n, i = n.next, i+1 {
+			if i%3 == 0 {
+				if prev == nil {
+					head = n.next
+				} else {
+					prev.next = n.next
+				}
+				continue
+			}
+			prev = n
+		}
+		for i := 0; i < nodes/3; i++ {
+			head = &heapNode{id: int64(nodes + i), value: float64(i), next: head}
+		}
+		// the collector has to walk the whole list
+		runtime.GC()
+		sum := 0.0
+		for n := head; n != nil; n = n.next {
+			sum += n.value
+		}
+		_ = sum // prevent optimization
+		duration := time.Since(start)
+		return float64(duration.Nanoseconds()) / 1000000.0
+	})
+
+	if anonMapper.mmap == nil {
+		fmt.Fprintf(os.Stderr, "off-heap: mmap not supported on %s, skipping\n", runtime.GOOS)
+		return totalTime
+	}
+
+	nodeSize := uint32(unsafe.Sizeof(offHeapNode{}))
+	mem, err := anonMapper.mmap(nodes*int(nodeSize) + 4096)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "off-heap: could not map region -> %v\n", err)
+		return totalTime
+	}
+	defer anonMapper.unmap(mem)
+
+	totalTime += measure("off-heap: mmap nodes", func() float64 {
+		start := time.Now()
+		h := &offHeap{mem: mem, used: 8, free: make(map[uint32]uint32)}
+		node := func(off uint32) *offHeapNode { return (*offHeapNode)(h.at(off)) }
+		push := func(head uint32, id int64, value float64) uint32 {
+			off, ok := h.alloc(nodeSize)
+			if !ok {
+				panic("off-heap region exhausted")
+			}
+			*node(off) = offHeapNode{id: id, value: value, next: head}
+			return off
+		}
+
+		var head uint32
+		for i := 0; i < nodes; i++ {
+			head = push(head, int64(i), float64(i)*0.5)
+		}
+		// unlinked nodes go straight back on the free list
+		var prev uint32
+		for off, i := head, 0; off != 0; i++ {
+			next := node(off).next
+			if i%3 == 0 {
+				if prev == 0 {
+					head = next
+				} else {
+					node(prev).next = next
+				}
+				h.release(off, nodeSize)
+			} else {
+				prev = off
+			}
+			off = next
+		}
+		// refilling reuses the freed blocks instead of growing the region
+		for i := 0; i < nodes/3; i++ {
+			head = push(head, int64(nodes+i), float64(i))
+		}
+		runtime.GC()
+		sum := 0.0
+		for off := head; off != 0; off = node(off).next {
+			sum += node(off).value
+		}
+		_ = sum // prevent optimization
+		duration := time.Since(start)
+		return float64(duration.Nanoseconds()) / 1000000.0
+	})
+
+	heap, mapped := results[len(results)-2], results[len(results)-1]
+	fmt.Fprintf(os.Stderr, "off-heap: heap %.3f ms (gc pause %.3f ms), mmap %.3f ms (gc pause %.3f ms)\n",
+		heap.Millis, heap.GC.PauseTotalMs, mapped.Millis, mapped.GC.PauseTotalMs)
+	return totalTime
+}
+
+// numaNode is one memory node and the cpus local to it
+type numaNode struct {
+	id      int
+	cpuList string // as the kernel prints it, e.g. "0-7,16-23"
+	cpus    []int
+}
+
+// numa hooks, memory_linux.go reads the topology from sysfs and implements
+// them with sched_setaffinity and mbind, elsewhere there's a single node
+var (
+	numaTopology = func() []numaNode { return nil }
+	// numaPin restricts the calling os thread to cpus until restore is called
+	numaPin func(cpus []int) (restore func(), err error)
+	// numaBind places mem's pages on node, it must run before the first touch
+	numaBind func(mem []byte, node int) error
+)
+
+// numa test measures read bandwidth for every pairing of the node a thread
+// runs on and the node its memory lives on, so the diagonal is local access
+// and everything else pays the interconnect
+func numaTest(sizeMB int, passes int) float64 {
+	nodes := numaTopology()
+	recordMetadata("numa nodes", strconv.Itoa(max(len(nodes), 1)))
+	for _, n := range nodes {
+		recordMetadata(fmt.Sprintf("numa node%d cpus", n.id), n.cpuList)
+	}
+	if path, err := exec.LookPath("numactl"); err == nil {
+		recordMetadata("numactl", path)
+	} else {
+		recordMetadata("numactl", "not found")
+	}
+
+	if len(nodes) < 2 || numaPin == nil || numaBind == nil || anonMapper.mmap == nil {
+		fmt.Fprintf(os.Stderr, "numa: %d node(s) on %s, skipping remote access test\n", max(len(nodes), 1), runtime.GOOS)
+		return 0
+	}
+
+	size := sizeMB * 1024 * 1024
+	bandwidth := func(cpuNode, memNode numaNode) (float64, error) {
+		// pin before mapping so the thread that touches is the one that reads
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		restore, err := numaPin(cpuNode.cpus)
+		if err != nil {
+			return 0, err
+		}
+		defer restore()
+
+		mem, err := anonMapper.mmap(size)
+		if err != nil {
+			return 0, err
+		}
+		defer anonMapper.unmap(mem)
+		if err := numaBind(mem, memNode.id); err != nil {
+			return 0, err
+		}
+		for i := 0; i < len(mem); i += 4096 {
+			mem[i] = 1
+		}
+
+		words := unsafe.Slice((*uint64)(unsafe.Pointer(&mem[0])), len(mem)/8)
+		start := time.Now()
+		var sum uint64
+		for p := 0; p < passes; p++ {
+			for _, w := range words {
+				sum += w
+			}
+		}
+		_ = sum // prevent optimization
+		return time.Since(start).Seconds(), nil
+	}
+
+	totalTime := 0.0
+	var local, remote []float64
+	for _, cpuNode := range nodes {
+		for _, memNode := range nodes {
+			name := fmt.Sprintf("numa cpu node%d mem node%d", cpuNode.id, memNode.id)
+			var gbps float64
+			var failure error
+			totalTime += measure(name, func() float64 {
+				seconds, err := bandwidth(cpuNode, memNode)
+				if err != nil {
+					failure = err
+					return 0
+				}
+				gbps = float64(size) * float64(passes) / seconds / 1e9
+				return seconds * 1000.0
+			})
+			if failure != nil {
+				fmt.Fprintf(os.Stderr, "%s: failed -> %v\n", name, failure)
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "%s: %.2f GB/s\n", name, gbps)
+			if cpuNode.id == memNode.id {
+				local = append(local, gbps)
+			} else {
+				remote = append(remote, gbps)
+			}
+		}
+	}
+
+	mean := func(values []float64) float64 {
+		total := 0.0
+		for _, v := range values {
+			total += v
+		}
+		return total / float64(len(values))
+	}
+	if len(local) > 0 && len(remote) > 0 {
+		penalty := (1 - mean(remote)/mean(local)) * 100
+		fmt.Fprintf(os.Stderr, "numa: local %.2f GB/s, remote %.2f GB/s, remote penalty %.1f%%\n", mean(local), mean(remote), penalty)
+		recordMetadata("numa remote penalty", fmt.Sprintf("%.1f%%", penalty))
+	}
+	return totalTime
+}
+
+// large object test allocates past the 32KB small-object limit, where each
+// make gets its own span straight from the heap, with every buffer dropped
+// right away or kept in a window of live buffers so the heap holds more
+func largeObjectTest(totalMB int) float64 {
+	sizes := []int{32*1024 + 1, 64 * 1024, 256 * 1024, 1024 * 1024, 8 * 1024 * 1024}
+	windows := []int{1, 32}
+
+	totalTime := 0.0
+	for _, window := range windows {
+		for _, size := range sizes {
+			count := max(totalMB*1024*1024/size, 1)
+			name := fmt.Sprintf("large object %dKB live %d", (size+1023)/1024, window)
+			totalTime += measure(name, func() float64 {
+				live := make([][]byte, window)
+				start := time.Now()
+				for i := 0; i < count; i++ {
+					buf := make([]byte, size)
+					buf[size-1] = byte(i)
+					live[i%window] = buf
+				}
+				duration := time.Since(start)
+				runtime.KeepAlive(live)
+				return float64(duration.Nanoseconds()) / 1000000.0
+			})
+			r := results[len(results)-1]
+			fmt.Fprintf(os.Stderr, "%s: %.0f MB/s, %.0f allocs/ms, %.1f gcs per GB\n", name,
+				float64(count*size)/(1024*1024)/(r.Millis/1000.0), float64(count)/r.Millis,
+				float64(r.GC.Cycles)/(float64(count*size)/(1024*1024*1024)))
+		}
+	}
+	return totalTime
+}
+
+// buffer zeroing test runs the same light per-buffer work on a fresh make
+// each time, on one reused buffer cleared with clear(), and on one reused
+// buffer left dirty, the gaps between them are what zeroing costs
+func bufferZeroingTest(totalMB int) float64 {
+	sizes := []int{4 * 1024, 64 * 1024, 1024 * 1024}
+	work := func(buf []byte, i int) byte {
+		// touch a byte per cache line, far cheaper than zeroing the buffer
+		var sum byte
+		for j := 0; j < len(buf); j += 64 {
+			buf[j] += byte(i)
+			sum += buf[j]
+		}
+		return sum
+	}
+
+	totalTime := 0.0
+	for _, size := range sizes {
+		count := totalMB * 1024 * 1024 / size
+		variants := []struct {
+			name string
+			run  func() byte
+		}{
+			{"fresh", func() byte {
+				var sum byte
+				for i := 0; i < count; i++ {
+					sum += work(make([]byte, size), i)
+				}
+				return sum
+			}},
+			{"reuse clear", func() byte {
+				var sum byte
+				buf := make([]byte, size)
+				for i := 0; i < count; i++ {
+					clear(buf)
+					sum += work(buf, i)
+				}
+				return sum
+			}},
+			{"reuse dirty", func() byte {
+				var sum byte
+				buf := make([]byte, size)
+				for i := 0; i < count; i++ {
+					sum += work(buf, i)
+				}
+				return sum
+			}},
+		}
+
+		var dirty float64
+		for _, v := range variants {
+			name := fmt.Sprintf("buffer zeroing %s %dKB", v.name, size/1024)
+			totalTime += measure(name, func() float64 {
+				start := time.Now()
+				sum := v.run()
+				_ = sum // prevent optimization
+				duration := time.Since(start)
+				return float64(duration.Nanoseconds()) / 1000000.0
+			})
+			ms := results[len(results)-1].Millis
+			if v.name == "reuse dirty" {
+				dirty = ms
+			}
+			fmt.Fprintf(os.Stderr, "%s: %.1f ns/buffer\n", name, ms*1000000.0/float64(count))
+		}
+		// the dirty run is the floor, whatever the others spend above it is zeroing
+		fresh, cleared := results[len(results)-3].Millis, results[len(results)-2].Millis
+		fmt.Fprintf(os.Stderr, "buffer zeroing %dKB: over dirty reuse, fresh (alloc+zero+gc) costs %.1f ns/buffer, clear %.1f ns/buffer\n", size/1024,
+			(fresh-dirty)*1000000.0/float64(count), (cleared-dirty)*1000000.0/float64(count))
+	}
+	return totalTime
+}
+
+// finalizedObject is a small heap object for the finalizer test
+type finalizedObject struct {
+	id      int64
+	payload [48]byte
+}
+
+// attachCleanup registers a runtime.AddCleanup callback bumping counter when
+// obj is collected, set by memory_cleanup.go on go1.24 and newer toolchains
+var attachCleanup func(obj *finalizedObject, counter *int64)
+
+// finalizer test allocates short-lived objects plain, with a finalizer and
+// with a cleanup, and runs the collector until every callback has fired,
+// finalized objects take an extra cycle to free and their callbacks run on
+// one goroutine, which is the throughput penalty
+func finalizerTest(objects int) float64 {
+	variants := []struct {
+		name   string
+		attach func(obj *finalizedObject, counter *int64)
+	}{
+		{"finalizer none", nil},
+		{"finalizer SetFinalizer", func(obj *finalizedObject, counter *int64) {
+			runtime.SetFinalizer(obj, func(*finalizedObject) { atomic.AddInt64(counter, 1) })
+		}},
+	}
+	if attachCleanup != nil {
+		variants = append(variants, struct {
+			name   string
+			attach func(obj *finalizedObject, counter *int64)
+		}{"finalizer AddCleanup", attachCleanup})
+	} else {
+		fmt.Fprintf(os.Stderr, "finalizer: runtime.AddCleanup needs go1.24, skipping\n")
+	}
+
+	totalTime := 0.0
+	for _, v := range variants {
+		var ran int64
+		totalTime += measure(v.name, func() float64 {
+			start := time.Now()
+			var sum int64
+			for i := 0; i < objects; i++ {
+				obj := &finalizedObject{id: int64(i)}
+				obj.payload[0] = byte(i)
+				if v.attach != nil {
+					v.attach(obj, &ran)
+				}
+				sum += obj.id
+			}
+			_ = sum // prevent optimization
+
+			// keep collecting until the callbacks have caught up
+			deadline := time.Now().Add(10 * time.Second)
+			for {
+				runtime.GC()
+				if v.attach == nil || atomic.LoadInt64(&ran) >= int64(objects) || time.Now().After(deadline) {
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+			duration := time.Since(start)
+			return float64(duration.Nanoseconds()) / 1000000.0
+		})
+		r := results[len(results)-1]
+		fmt.Fprintf(os.Stderr, "%s: %.1f ns/object, %d gcs", v.name, r.Millis*1000000.0/float64(objects), r.GC.Cycles)
+		if v.attach != nil {
+			fmt.Fprintf(os.Stderr, ", %d/%d callbacks ran", atomic.LoadInt64(&ran), objects)
+		}
+		fmt.Fprintln(os.Stderr)
+	}
+	return totalTime
+}
+
+// ttlCache is a size-bounded cache where every entry expires ttl ticks after
+// it was stored, all entries share the ttl so insertion order is expiry order
+// and one fifo serves both expiry and capacity eviction
+type ttlCache struct {
+	entries  map[int64]*cacheEntry
+	fifo     []*cacheEntry
+	capacity int
+	ttl      int64
+
+	hits, misses, expired, evicted int
+}
+
+type cacheEntry struct {
+	key     int64
+	value   []byte
+	expires int64
+	dead    bool // replaced or expired, left in the fifo until it's popped
+}
+
+func newTTLCache(capacity int, ttl int64) *ttlCache {
+	return &ttlCache{entries: make(map[int64]*cacheEntry, capacity), capacity: capacity, ttl: ttl}
+}
+
+func (c *ttlCache) get(key int64, now int64) ([]byte, bool) {
+	e, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	if now >= e.expires {
+		delete(c.entries, key)
+		e.dead = true
+		c.expired++
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	return e.value, true
+}
+
+func (c *ttlCache) put(key int64, value []byte, now int64) {
+	if old, ok := c.entries[key]; ok {
+		old.dead = true
+	}
+	e := &cacheEntry{key: key, value: value, expires: now + c.ttl}
+	c.entries[key] = e
+	c.fifo = append(c.fifo, e)
+
+	// drop dead entries and anything expired from the front, then the oldest
+	// live ones while over capacity
+	for len(c.fifo) > 0 {
+		front := c.fifo[0]
+		switch {
+		case front.dead:
+		case now >= front.expires:
+			delete(c.entries, front.key)
+			c.expired++
+		case len(c.entries) > c.capacity:
+			delete(c.entries, front.key)
+			c.evicted++
+		default:
+			return
+		}
+		c.fifo[0] = nil
+		c.fifo = c.fifo[1:]
+	}
+}
+
+// ttl cache test drives the cache with zipf skewed random reads, a miss
+// allocates a fresh value and stores it, so the churn follows the hit rate
+func ttlCacheTest(ops int, keySpace int, capacity int, ttl int64) float64 {
+	rng := rand.New(rand.NewSource(42))
+	zipf := rand.NewZipf(rng, 1.05, 1, uint64(keySpace-1))
+	keys := make([]int64, ops)
+	for i := range keys {
+		keys[i] = int64(zipf.Uint64())
+	}
+
+	cache := newTTLCache(capacity, ttl)
+	var allocated int64
+	totalTime := measure("ttl cache", func() float64 {
+		start := time.Now()
+		var sum int
+		for now, key := range keys {
+			if value, ok := cache.get(key, int64(now)); ok {
+				sum += int(value[0])
+				continue
+			}
+			// values between 256B and 4KB, sized off the key so they're stable
+			value := make([]byte, 256+int(key*7919)%3840)
+			value[0] = byte(key)
+			allocated += int64(len(value))
+			cache.put(key, value, int64(now))
+		}
+		_ = sum // prevent optimization
+		duration := time.Since(start)
+		return float64(duration.Nanoseconds()) / 1000000.0
+	})
+
+	r := results[len(results)-1]
+	fmt.Fprintf(os.Stderr, "ttl cache: %.1f%% hit rate, %d expired, %d evicted, %.1f MB/s churn, %d gcs, %.3f ms gc pause\n",
+		float64(cache.hits)*100/float64(ops), cache.expired, cache.evicted,
+		float64(allocated)/(1024*1024)/(r.Millis/1000.0), r.GC.Cycles, r.GC.PauseTotalMs)
+	return totalTime
+}
+
+// escapePoint is small enough that copying it around is cheap, so the only
+// difference between the escape variants is where it lives
+type escapePoint struct {
+	x, y, z float64
+}
+
+type escapeShape interface {
+	norm() float64
+}
+
+func (p escapePoint) norm() float64 { return p.x*p.x + p.y*p.y + p.z*p.z }
+
+// noinline keeps the compiler from seeing through the calls and undoing
+// the escape, go build -gcflags=-m shows which values move to the heap
+//
+//go:noinline
+func pointByValue(i int) escapePoint {
+	return escapePoint{float64(i), float64(i + 1), float64(i + 2)}
+}
+
+//go:noinline
+func pointByPointer(i int) *escapePoint {
+	return &escapePoint{float64(i), float64(i + 1), float64(i + 2)}
+}
+
+// lastShape keeps the boxed value reachable, which forces the box onto the heap
+var lastShape escapeShape
+
+//go:noinline
+func boxPoint(i int) escapeShape {
+	lastShape = escapePoint{float64(i), float64(i + 1), float64(i + 2)}
+	return lastShape
+}
+
+// stack vs heap runs the same hot loop with the value kept on the stack,
+// returned through a pointer, and boxed into an interface
+func stackVsHeapTest(iterations int) float64 {
+	variants := []struct {
+		name string
+		run  func(i int) float64
+	}{
+		{"escape stack value", func(i int) float64 { return pointByValue(i).norm() }},
+		{"escape returned pointer", func(i int) float64 { return pointByPointer(i).norm() }},
+		{"escape interface boxing", func(i int) float64 { return boxPoint(i).norm() }},
+	}
+
+	totalTime := 0.0
+	for _, v := range variants {
+		totalTime += measure(v.name, func() float64 {
+			start := time.Now()
+			var sum float64
+			for i := 0; i < iterations; i++ {
+				sum += v.run(i)
+			}
+			_ = sum // prevent optimization
+			duration := time.Since(start)
+			return float64(duration.Nanoseconds()) / 1000000.0
+		})
+		r := results[len(results)-1]
+		fmt.Fprintf(os.Stderr, "%s: %.2f ns/op, %.3f allocs/op\n", v.name,
+			r.Millis*1000000.0/float64(iterations), float64(r.GC.Objects)/float64(iterations))
+	}
+	return totalTime
+}
+
+// appendGrowth fills a slice of n elements one append at a time and counts
+// what the reallocations cost, chunk 0 leaves growth to append itself
+func appendGrowth[T any](n int, prealloc bool, chunk int) (elapsed time.Duration, copied int64, reallocs int) {
+	var zero T
+	size := int64(unsafe.Sizeof(zero))
+
+	start := time.Now()
+	var s []T
+	if prealloc {
+		s = make([]T, 0, n)
+	}
+	for i := 0; i < n; i++ {
+		before := cap(s)
+		if chunk > 0 && len(s) == cap(s) {
+			// grow by exactly chunk, slices.Grow would round up to doubling
+			grown := make([]T, len(s), cap(s)+chunk)
+			copy(grown, s)
+			s = grown
+		}
+		s = append(s, zero)
+		// a cap change means the old backing array was copied over
+		if cap(s) != before {
+			if before > 0 {
+				copied += int64(i) * size
+			}
+			reallocs++
+		}
+	}
+	elapsed = time.Since(start)
+	_ = s[len(s)-1] // prevent optimization
+	return elapsed, copied, reallocs
+}
+
+// append growth test grows slices from empty by appending, with no capacity,
+// the exact capacity up front, or in fixed chunks, for a few element sizes
+func appendGrowthTest(elements int) float64 {
+	type elem64 [8]int64
+	type elem256 [32]int64
+
+	// fixed chunks copy quadratically, so size them off the final length
+	chunk := max(elements/16, 1)
+	strategies := []struct {
+		name     string
+		prealloc bool
+		chunk    int
+	}{
+		{"append", false, 0},
+		{"exact cap", true, 0},
+		{"chunked", false, chunk},
+	}
+	sizes := []struct {
+		name string
+		run  func(prealloc bool, chunk int) (time.Duration, int64, int)
+	}{
+		{"8B", func(p bool, c int) (time.Duration, int64, int) { return appendGrowth[int64](elements, p, c) }},
+		{"64B", func(p bool, c int) (time.Duration, int64, int) { return appendGrowth[elem64](elements, p, c) }},
+		{"256B", func(p bool, c int) (time.Duration, int64, int) { return appendGrowth[elem256](elements, p, c) }},
+	}
+
+	totalTime := 0.0
+	for _, size := range sizes {
+		for _, st := range strategies {
+			var copied int64
+			var reallocs int
+			name := fmt.Sprintf("append growth %s %s", st.name, size.name)
+			totalTime += measure(name, func() float64 {
+				var duration time.Duration
+				duration, copied, reallocs = size.run(st.prealloc, st.chunk)
+				return float64(duration.Nanoseconds()) / 1000000.0
+			})
+			fmt.Fprintf(os.Stderr, "%s: %d reallocs, %.1f MB copied\n", name, reallocs, float64(copied)/(1024*1024))
+		}
+	}
+	return totalTime
+}
+
+// heapInUse forces a collection and returns the live heap, so the difference
+// between two calls is what stayed reachable in between
+func heapInUse() uint64 {
+	runtime.GC()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.HeapAlloc
+}
+
+// map overhead test inserts entries keys into a set-like and a blob map,
+// then looks every key up again, and reports the heap each entry costs
+func mapOverheadTest(entries int) float64 {
+	totalTime := 0.0
+	report := func(name string, before, after uint64) {
+		insert := results[len(results)-2].Millis
+		lookup := results[len(results)-1].Millis
+		fmt.Fprintf(os.Stderr, "%s: %.1f M inserts/s, %.1f M lookups/s, %.1f heap bytes/entry\n", name,
+			float64(entries)/(insert*1000.0), float64(entries)/(lookup*1000.0),
+			(float64(after)-float64(before))/float64(entries))
+	}
+
+	// int64 keys, no values
+	before := heapInUse()
+	set := make(map[int64]struct{})
+	totalTime += measure("map int64 set insert", func() float64 {
+		start := time.Now()
+		for i := 0; i < entries; i++ {
+			set[int64(i)*2654435761] = struct{}{}
+		}
+		duration := time.Since(start)
+		return float64(duration.Nanoseconds()) / 1000000.0
+	})
+	totalTime += measure("map int64 set lookup", func() float64 {
+		start := time.Now()
+		found := 0
+		for i := 0; i < entries; i++ {
+			if _, ok := set[int64(i)*2654435761]; ok {
+				found++
+			}
+		}
+		_ = found // prevent optimization
+		duration := time.Since(start)
+		return float64(duration.Nanoseconds()) / 1000000.0
+	})
+	after := heapInUse()
+	runtime.KeepAlive(set)
+	report("map int64 set", before, after)
+	set = nil
+
+	// string keys with small byte slice values, keys are built up front so
+	// only the map and the values count towards the growth
+	keys := make([]string, entries)
+	for i := range keys {
+		keys[i] = "key-" + strconv.Itoa(i)
+	}
+	before = heapInUse()
+	blobs := make(map[string][]byte)
+	totalTime += measure("map string bytes insert", func() float64 {
+		start := time.Now()
+		for i, key := range keys {
+			value := make([]byte, 16)
+			value[0] = byte(i)
+			blobs[key] = value
+		}
+		duration := time.Since(start)
+		return float64(duration.Nanoseconds()) / 1000000.0
+	})
+	totalTime += measure("map string bytes lookup", func() float64 {
+		start := time.Now()
+		sum := 0
+		for _, key := range keys {
+			sum += int(blobs[key][0])
+		}
+		_ = sum // prevent optimization
+		duration := time.Since(start)
+		return float64(duration.Nanoseconds()) / 1000000.0
+	})
+	after = heapInUse()
+	runtime.KeepAlive(blobs)
+	report("map string bytes", before, after)
+
+	return totalTime
+}
+
+// string interning test retains a stream of keys with heavy repetition, once
+// as a fresh string per record and once through an intern table, comparing
+// speed and how much heap stays live afterwards
+func stringInterningTest(records, distinct int) float64 {
+	// zipf skewed key ids so a few keys dominate, like real log or event streams
+	ids := make([]int64, records)
+	zipf := rand.NewZipf(rand.New(rand.NewSource(42)), 1.1, 1, uint64(distinct-1))
+	for i := range ids {
+		ids[i] = int64(zipf.Uint64())
+	}
+
+	variants := []struct {
+		name   string
+		retain func(buf []byte) string
+	}{
+		{"string intern naive", func(buf []byte) string { return string(buf) }},
+		{"string intern table", nil},
+	}
+
+	totalTime := 0.0
+	for _, v := range variants {
+		table := make(map[string]string)
+		retain := v.retain
+		if retain == nil {
+			retain = func(buf []byte) string {
+				// the map lookup with string(buf) doesn't allocate
+				if s, ok := table[string(buf)]; ok {
+					return s
+				}
+				s := string(buf)
+				table[s] = s
+				return s
+			}
+		}
+
+		retained := make([]string, records)
+		before := heapInUse()
+		totalTime += measure(v.name, func() float64 {
+			start := time.Now()
+			// every record's key is rebuilt in a scratch buffer, as if parsed
+			buf := make([]byte, 0, 64)
+			for i, id := range ids {
+				buf = strconv.AppendInt(append(buf[:0], "tenant/eu-west/session-"...), id, 10)
+				retained[i] = retain(buf)
+			}
+			duration := time.Since(start)
+			return float64(duration.Nanoseconds()) / 1000000.0
+		})
+		after := heapInUse()
+		runtime.KeepAlive(retained)
+		runtime.KeepAlive(table)
+
+		r := results[len(results)-1]
+		fmt.Fprintf(os.Stderr, "%s: %.1f M records/s, %.1f MB live", v.name,
+			float64(records)/(r.Millis*1000.0), (float64(after)-float64(before))/(1024*1024))
+		if len(table) > 0 {
+			fmt.Fprintf(os.Stderr, " (%d unique of %d)", len(table), records)
+		}
+		fmt.Fprintln(os.Stderr)
+	}
+	return totalTime
+}
+
+// gcSnapshot is what runtime/metrics says about the gc at one point in time
+type gcSnapshot struct {
+	cycles     uint64
+	forced     uint64
+	allocBytes uint64
+	allocObjs  uint64
+	heapLive   uint64
+	heapGoal   uint64
+	pauses     *metrics.Float64Histogram
+}
+
+var gcMetricNames = []string{
+	"/gc/cycles/total:gc-cycles",
+	"/gc/cycles/forced:gc-cycles",
+	"/gc/heap/allocs:bytes",
+	"/gc/heap/allocs:objects",
+	"/gc/heap/live:bytes",
+	"/gc/heap/goal:bytes",
+	"/sched/pauses/total/gc:seconds",
+}
+
+func readGCSnapshot() gcSnapshot {
+	samples := make([]metrics.Sample, len(gcMetricNames))
+	for i, name := range gcMetricNames {
+		samples[i].Name = name
+	}
+	metrics.Read(samples)
+
+	// metrics the runtime doesn't know read as KindBad, treat them as zero
+	value := func(i int) uint64 {
+		if samples[i].Value.Kind() != metrics.KindUint64 {
+			return 0
+		}
+		return samples[i].Value.Uint64()
+	}
+	snapshot := gcSnapshot{
+		cycles:     value(0),
+		forced:     value(1),
+		allocBytes: value(2),
+		allocObjs:  value(3),
+		heapLive:   value(4),
+		heapGoal:   value(5),
+	}
+	if samples[6].Value.Kind() == metrics.KindFloat64Histogram {
+		snapshot.pauses = samples[6].Value.Float64Histogram()
+	}
+	return snapshot
+}
+
+// gcStats is the gc activity during one test, heap live/goal are the
+// values right after it
+type gcStats struct {
+	Cycles       uint64
+	Forced       uint64
+	PauseTotalMs float64
+	PauseP50Ms   float64
+	PauseP99Ms   float64
+	PauseMaxMs   float64
+	Allocated    uint64
+	Objects      uint64
+	HeapLive     uint64
+	HeapGoal     uint64
+}
+
+func gcDelta(before, after gcSnapshot) gcStats {
+	stats := gcStats{
+		Cycles:    after.cycles - before.cycles,
+		Forced:    after.forced - before.forced,
+		Allocated: after.allocBytes - before.allocBytes,
+		Objects:   after.allocObjs - before.allocObjs,
+		HeapLive:  after.heapLive,
+		HeapGoal:  after.heapGoal,
+	}
+	if before.pauses == nil || after.pauses == nil {
+		return stats
+	}
+
+	// the histogram is cumulative, the difference holds this test's pauses
+	counts := make([]uint64, len(after.pauses.Counts))
+	total := uint64(0)
+	for i := range counts {
+		counts[i] = after.pauses.Counts[i] - before.pauses.Counts[i]
+		total += counts[i]
+	}
+	if total == 0 {
+		return stats
+	}
+
+	// buckets only bound each pause, so times are estimated from the
+	// bucket midpoints and percentiles report the bucket's upper edge
+	buckets := after.pauses.Buckets
+	upper := func(i int) float64 {
+		if math.IsInf(buckets[i+1], 1) {
+			return buckets[i]
+		}
+		return buckets[i+1]
+	}
+	p50 := (total + 1) / 2
+	p99 := (total*99 + 99) / 100
+	seen := uint64(0)
+	for i, n := range counts {
+		if n == 0 {
+			continue
+		}
+		low := buckets[i]
+		if math.IsInf(low, -1) {
+			low = 0
+		}
+		stats.PauseTotalMs += float64(n) * (low + upper(i)) / 2 * 1000
+		stats.PauseMaxMs = upper(i) * 1000
+		if seen < p50 && seen+n >= p50 {
+			stats.PauseP50Ms = upper(i) * 1000
+		}
+		if seen < p99 && seen+n >= p99 {
+			stats.PauseP99Ms = upper(i) * 1000
+		}
+		seen += n
+	}
+	return stats
+}
+
+// testResult is one test's wall time plus the gc work it caused
+type testResult struct {
+	Name    string
+	Millis  float64
+	GC      gcStats
+	PeakRSS int64
+}
+
+var results []testResult
+
+// metadata describes the machine the results came from, printed after them
+var metadata [][2]string
+
+func recordMetadata(key, value string) {
+	metadata = append(metadata, [2]string{key, value})
+}
+
+// measure runs a test between two gc snapshots and records both, plus the
+// peak rss reached while it ran
+func measure(name string, test func() float64) float64 {
+	resetPeakRSS()
+	swapBefore := swappedBytes()
+	before := readGCSnapshot()
+	millis := test()
+	after := readGCSnapshot()
+	results = append(results, testResult{Name: name, Millis: millis, GC: gcDelta(before, after), PeakRSS: peakRSS()})
+
+	// timings taken while pages were going to swap measure the disk, not memory
+	if swapAfter := swappedBytes(); swapAfter > 0 && swapAfter > swapBefore {
+		fmt.Fprintf(os.Stderr, "warning: %s pushed %.1f MB to swap, its timing is unreliable\n", name,
+			float64(swapAfter-swapBefore)/(1024*1024))
+	}
+	return millis
+}
+
+// rss hooks, peak rss is in bytes and -1 when the platform can't tell,
+// resetPeakRSS only works on linux, elsewhere the peak is since process start
+var (
+	peakRSS      = func() int64 { return -1 }
+	resetPeakRSS = func() {}
+	swappedBytes = func() int64 { return -1 }
+)
+
+// printResults writes the per-test gc table to stderr, stdout keeps only the total
+func printResults() {
+	// sweep runs tag their names with the limit and ballast
+	width := 24
+	for _, r := range results {
+		width = max(width, len(r.Name))
+	}
+	fmt.Fprintf(os.Stderr, "%-*s %10s %6s %6s %10s %9s %9s %9s %10s %10s %9s %9s %9s\n",
+		width, "test", "ms", "gcs", "forced", "pause ms", "p50 ms", "p99 ms", "max ms", "alloc MB", "objects", "live MB", "goal MB", "rss MB")
+	const mb = 1024 * 1024
+	for _, r := range results {
+		rss := "n/a"
+		if r.PeakRSS >= 0 {
+			rss = fmt.Sprintf("%.1f", float64(r.PeakRSS)/mb)
+		}
+		fmt.Fprintf(os.Stderr, "%-*s %10.3f %6d %6d %10.3f %9.3f %9.3f %9.3f %10.1f %10d %9.1f %9.1f %9s\n",
+			width, r.Name, r.Millis, r.GC.Cycles, r.GC.Forced, r.GC.PauseTotalMs, r.GC.PauseP50Ms, r.GC.PauseP99Ms, r.GC.PauseMaxMs,
+			float64(r.GC.Allocated)/mb, r.GC.Objects, float64(r.GC.HeapLive)/mb, float64(r.GC.HeapGoal)/mb, rss)
+	}
+	for _, m := range metadata {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", m[0], m[1])
+	}
+}
+
+// startPressure runs a background allocator for the rest of the tests, it
+// holds retainMB live in 64KB chunks and allocates churnMBps worth of new
+// chunks every second, each one replacing a random retained chunk (or just
+// dropped when nothing is retained), so the gc always has old garbage to find
+func startPressure(churnMBps int, retainMB int) (stop func()) {
+	const chunk = 64 * 1024
+	const tick = 10 * time.Millisecond
+	recordMetadata("background pressure", fmt.Sprintf("%d MB/s churn, %d MB retained", churnMBps, retainMB))
+
+	retained := make([][]byte, retainMB*1024*1024/chunk)
+	for i := range retained {
+		retained[i] = make([]byte, chunk)
+	}
+
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	var allocated int64
+	started := time.Now()
+	go func() {
+		defer close(finished)
+		rng := rand.New(rand.NewSource(7))
+		ticker := time.NewTicker(tick)
+		defer ticker.Stop()
+		// fractional chunks carry over so low rates still come out right
+		perTick := float64(churnMBps) * 1024 * 1024 / chunk * tick.Seconds()
+		owed := 0.0
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			owed += perTick
+			for ; owed >= 1; owed-- {
+				buf := make([]byte, chunk)
+				buf[0] = 1
+				if len(retained) > 0 {
+					retained[rng.Intn(len(retained))] = buf
+				}
+				allocated += chunk
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-finished
+		elapsed := time.Since(started).Seconds()
+		fmt.Fprintf(os.Stderr, "background pressure: %.1f MB/s achieved of %d MB/s, %d MB retained, over %.1f s\n",
+			float64(allocated)/(1024*1024)/elapsed, churnMBps, retainMB, elapsed)
+		runtime.KeepAlive(retained)
+	}
+}
+
+// parseRatios reads a comma separated list of short-lived per long-lived counts
+func parseRatios(list string) ([]int, error) {
+	var ratios []int
+	for _, field := range strings.Split(list, ",") {
+		ratio, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || ratio < 0 {
+			return nil, fmt.Errorf("invalid generational ratio %q", field)
+		}
+		ratios = append(ratios, ratio)
+	}
+	return ratios, nil
+}
+
+// parseMemoryLimits reads a comma separated list of limits in MB, "off"
+// meaning no limit
+func parseMemoryLimits(list string) ([]int64, error) {
+	var limits []int64
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if field == "off" {
+			limits = append(limits, math.MaxInt64)
+			continue
+		}
+		mb, err := strconv.Atoi(field)
+		if err != nil || mb <= 0 {
+			return nil, fmt.Errorf("invalid memory limit %q", field)
+		}
+		limits = append(limits, int64(mb)*1024*1024)
+	}
+	return limits, nil
+}
+
+// memory limit sweep reruns the allocation tests under each GOMEMLIMIT,
+// without and (if ballastMB > 0) with a heap ballast, so the gc columns show
+// how collection frequency follows the limit; the runs stay out of the total
+func memoryLimitSweep(limits []int64, ballastMB int, scaleFactor int) {
+	original := debug.SetMemoryLimit(-1)
+	defer debug.SetMemoryLimit(original)
+
+	ballasts := []int{0}
+	if ballastMB > 0 {
+		ballasts = append(ballasts, ballastMB)
+	}
+	for _, limit := range limits {
+		label := "off"
+		if limit != math.MaxInt64 {
+			label = fmt.Sprintf("%dMB", limit/(1024*1024))
+		}
+		for _, mb := range ballasts {
+			// a ballast is allocated but never touched, it only moves the
+			// heap goal up by its size
+			ballast := make([]byte, mb*1024*1024)
+			debug.SetMemoryLimit(limit)
+			runtime.GC()
+
+			suffix := fmt.Sprintf(" [limit=%s ballast=%dMB]", label, mb)
+			measure("allocation patterns"+suffix, func() float64 { return allocationPatternsTest(10000 * scaleFactor) })
+			measure("gc stress"+suffix, func() float64 { return gcStressTest(4, 2500*scaleFactor) })
+
+			runtime.KeepAlive(ballast)
+			debug.SetMemoryLimit(original)
+		}
+	}
+}
+
+func main() {
+	memoryLimits := flag.String("memlimits", "", "also rerun the allocation tests under these GOMEMLIMIT values in MB, e.g. off,64,256")
+	ballastMB := flag.Int("ballast-mb", 0, "with -memlimits, also rerun each limit with a heap ballast of this size")
+	pressureMBps := flag.Int("pressure-mbps", 0, "allocate this many MB/s in a background goroutine while the tests run")
+	pressureRetainMB := flag.Int("pressure-retain-mb", 0, "with background pressure, keep this many MB of it live")
+	tlbMB := flag.Int("tlb-mb", 0, "run the tlb stress test over a mapping of this many MB (a power of two, at least 2), 0 skips it")
+	tlbHugePages := flag.Bool("tlb-hugepages", false, "with -tlb-mb, also run the tlb stress test on transparent huge pages")
+	genRatios := flag.String("gen-ratios", "100,20,5,1", "short-lived to long-lived ratios for the generational mix test run with -extended")
+	workingSetMB := flag.Int("working-set-mb", 0, "also sweep random reads over working sets up to this many MB, 0 skips it")
+	extended := flag.Bool("extended", false, "also run the go-only tests, reported on stderr and kept out of the total")
+	flag.Parse()
+	if *pressureRetainMB < 0 {
+		fmt.Fprintf(os.Stderr, "-pressure-retain-mb must not be negative, got %d\n", *pressureRetainMB)
+		os.Exit(1)
+	}
+	// the largest stride is 2MB and the line count is used as a mask, the
+	// byte size also has to fit an int on 32-bit builds
+	if *tlbMB != 0 && (*tlbMB < 2 || *tlbMB&(*tlbMB-1) != 0 || *tlbMB > math.MaxInt/(1024*1024)) {
+		fmt.Fprintf(os.Stderr, "-tlb-mb must be a power of two between 2 and %d, got %d\n", math.MaxInt/(1024*1024), *tlbMB)
+		os.Exit(1)
+	}
+	// list flags are parsed up front so a typo fails before any test runs
+	ratios, err := parseRatios(*genRatios)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	var limits []int64
+	if *memoryLimits != "" {
+		if limits, err = parseMemoryLimits(*memoryLimits); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	scaleFactor := 1
+	
+	if flag.NArg() > 0 {
+		if factor, err := strconv.Atoi(flag.Arg(0)); err == nil && factor > 0 {
+			scaleFactor = factor
+		} else {
+			fmt.Fprintf(os.Stderr, "Invalid scale factor. Using default 1.\n")
+		}
+	}
+	
+	var stopPressure func()
+	if *pressureMBps > 0 || *pressureRetainMB > 0 {
+		stopPressure = startPressure(*pressureMBps, *pressureRetainMB)
+	}
+
+	totalTime := 0.0
+	
+	totalTime += measure("allocation patterns", func() float64 { return allocationPatternsTest(10000 * scaleFactor) })
+	totalTime += measure("gc stress", func() float64 { return gcStressTest(4, 2500*scaleFactor) })
+	totalTime += measure("cache locality", func() float64 { return cacheLocalityTest(5000 * scaleFactor) })
+	if *workingSetMB > 0 {
+		// opt-in and go only, so it stays out of the total
+		measure("working set sweep", func() float64 { return workingSetSweep(*workingSetMB*1024*1024, 1000000) })
+	}
+	totalTime += measure("memory pool", func() float64 { return memoryPoolTest(8000 * scaleFactor) })
+	totalTime += measure("memory intensive", func() float64 { return memoryIntensiveTest(100 * scaleFactor) })
+	if *tlbMB > 0 {
+		// opt-in and go only, so it stays out of the total
+		tlbStressTest(*tlbMB, 2000000*scaleFactor, *tlbHugePages)
+	}
+
+	// opt-in and go only, so they stay out of the total
+	if *extended {
+		measure("fragmentation", func() float64 { return fragmentationTest(10000 * scaleFactor) })
+		measure("typed arena", func() float64 { return typedArenaTest(8000 * scaleFactor) })
+		memoryPoolParallelTest(max(4, runtime.NumCPU()), 200000*scaleFactor)
+		measure("stream bandwidth", func() float64 { return streamBandwidthTest(100*scaleFactor, runtime.NumCPU()) })
+		syncPoolTest(4, 20000*scaleFactor, []int{64, 1024, 16 * 1024})
+		falseSharingTest(runtime.NumCPU(), 1000000*scaleFactor)
+		mmapVsMakeTest(256, 2*scaleFactor)
+		measure("huge pages", func() float64 { return hugePageTest(256, 2000000*scaleFactor) })
+		stackVsHeapTest(5000000 * scaleFactor)
+		appendGrowthTest(1000000 * scaleFactor)
+		mapOverheadTest(2000000 * scaleFactor)
+		stringInterningTest(2000000*scaleFactor, 50000)
+		offHeapTest(1000000 * scaleFactor)
+		numaTest(256, 4)
+		largeObjectTest(512 * scaleFactor)
+		bufferZeroingTest(1024 * scaleFactor)
+		finalizerTest(500000 * scaleFactor)
+		ttlCacheTest(2000000*scaleFactor, 200000, 16384, 30000)
+		generationalMixTest(2000000*scaleFactor, ratios)
+		sizeClassTest(64 * scaleFactor)
+	}
+
+	if len(limits) > 0 {
+		memoryLimitSweep(limits, *ballastMB, scaleFactor)
+	}
+
+	if stopPressure != nil {
+		stopPressure()
+	}
+
+	printResults()
 	fmt.Printf("%.3f\n", totalTime)
 }
\ No newline at end of file
@@ -86,8 +86,17 @@ fi
 if [ $? -ne 0 ]; then echo "C++ compilation failed. Stopping."; exit 1; fi
 
 echo "Compiling Go code..."
-go build -ldflags="-s -w" -gcflags="-B" -o "memory_go${EXE_EXT}" memory.go
-if [ $? -ne 0 ]; then echo "Go compilation failed. Stopping."; exit 1; fi
+# go: builds as a module so platform files (memory_linux.go, memory_unix.go, memory_windows.go) get picked up
+# on toolchains with the arena experiment, GOEXPERIMENT=arenas also builds memory_arenas.go
+# go won't build a directory that also holds .c files, so the go sources
+# build from a copy of their own
+rm -rf go_build && mkdir go_build && cp *.go go_build/
+cd go_build
+go mod init memory_bench > /dev/null 2>&1
+go build -ldflags="-s -w" -gcflags="-B" -o "../memory_go${EXE_EXT}" .
+go_status=$?
+cd ..
+if [ $go_status -ne 0 ]; then echo "Go compilation failed. Stopping."; exit 1; fi
 
 # julia doesn't need compilation, it's JIT compiled
 echo "Julia ready (JIT compiled at runtime)"
@@ -203,6 +212,7 @@ fi
 # cleanup generated files
 echo "Cleaning up generated files..."
 rm -f Cargo.toml Cargo.lock
+rm -rf go_build
 rm -rf target
 
 echo "All done! Thanks for running this comprehensive memory management benchmark!"
\ No newline at end of file
@@ -0,0 +1,23 @@
+//go:build goexperiment.arenas
+
+package main
+
+import "arena"
+
+func init() {
+	runtimeArenaRecords = arenaRecords
+}
+
+// arenaRecords mirrors the homegrown typed arena in typedArenaTest, the
+// runtime arena is gc-aware so unlike ours it could hold pointers too
+func arenaRecords(iterations int) {
+	a := arena.NewArena()
+	records := arena.MakeSlice[*poolRecord](a, iterations, iterations)
+	for i := 0; i < iterations; i++ {
+		r := arena.New[poolRecord](a)
+		r.id = int64(i)
+		r.values[0] = float64(i)
+		records[i] = r
+	}
+	a.Free()
+}
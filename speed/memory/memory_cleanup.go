@@ -0,0 +1,15 @@
+//go:build go1.24
+
+package main
+
+import (
+	"runtime"
+	"sync/atomic"
+)
+
+func init() {
+	attachCleanup = func(obj *finalizedObject, counter *int64) {
+		// the argument must not reference obj, or it never becomes unreachable
+		runtime.AddCleanup(obj, func(counter *int64) { atomic.AddInt64(counter, 1) }, counter)
+	}
+}
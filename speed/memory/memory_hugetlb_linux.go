@@ -0,0 +1,11 @@
+//go:build !arm
+
+package main
+
+import "syscall"
+
+// mmapHugeTLB takes explicit 2MB pages from the hugetlbfs pool, it fails
+// unless pages were reserved beforehand
+func mmapHugeTLB(size int) ([]byte, error) {
+	return syscall.Mmap(-1, 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_PRIVATE|syscall.MAP_ANON|syscall.MAP_HUGETLB)
+}
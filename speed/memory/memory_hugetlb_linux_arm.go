@@ -0,0 +1,9 @@
+package main
+
+import "errors"
+
+// package syscall has no MAP_HUGETLB for linux/arm, the hugetlb row just
+// reports the error like it does when no pages are reserved
+func mmapHugeTLB(size int) ([]byte, error) {
+	return nil, errors.New("MAP_HUGETLB is not available on linux/arm")
+}
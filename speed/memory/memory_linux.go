@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+func init() {
+	hugePageMappers = []pageMapper{
+		{"4KB", func(size int) ([]byte, error) { return mmapAdvised(size, syscall.MADV_NOHUGEPAGE) }, syscall.Munmap},
+		{"thp", func(size int) ([]byte, error) { return mmapAdvised(size, syscall.MADV_HUGEPAGE) }, syscall.Munmap},
+		{"hugetlb", mmapHugeTLB, syscall.Munmap},
+	}
+	minorFaults = func() int64 {
+		var usage syscall.Rusage
+		if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+			return -1
+		}
+		return int64(usage.Minflt)
+	}
+	peakRSS = func() int64 { return procStatusBytes("VmHWM:") }
+	swappedBytes = func() int64 { return procStatusBytes("VmSwap:") }
+	resetPeakRSS = func() {
+		// writing 5 to clear_refs resets VmHWM to the current rss, linux 4.0+
+		os.WriteFile("/proc/self/clear_refs", []byte("5"), 0)
+	}
+	numaTopology = sysfsNumaTopology
+	numaPin = pinThread
+	numaBind = mbindNode
+}
+
+// mmapAdvised maps private anonymous memory and asks the kernel to back it
+// with (MADV_HUGEPAGE) or without (MADV_NOHUGEPAGE) transparent huge pages
+func mmapAdvised(size int, advice int) ([]byte, error) {
+	mem, err := syscall.Mmap(-1, 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_PRIVATE|syscall.MAP_ANON)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Madvise(mem, advice); err != nil {
+		syscall.Munmap(mem)
+		return nil, err
+	}
+	return mem, nil
+}
+
+// sysfsNumaTopology lists the nodes that have cpus, memory-only nodes can't
+// run the test thread so they're left out
+func sysfsNumaTopology() []numaNode {
+	dirs, _ := filepath.Glob("/sys/devices/system/node/node[0-9]*")
+	var nodes []numaNode
+	for _, dir := range dirs {
+		id, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(dir), "node"))
+		if err != nil {
+			continue
+		}
+		raw, err := os.ReadFile(filepath.Join(dir, "cpulist"))
+		if err != nil {
+			continue
+		}
+		cpuList := strings.TrimSpace(string(raw))
+		cpus, err := parseCPUList(cpuList)
+		if err != nil || len(cpus) == 0 {
+			continue
+		}
+		nodes = append(nodes, numaNode{id: id, cpuList: cpuList, cpus: cpus})
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].id < nodes[j].id })
+	return nodes
+}
+
+// parseCPUList expands the kernel's "0-3,8,10-11" list format
+func parseCPUList(list string) ([]int, error) {
+	var cpus []int
+	for _, field := range strings.Split(list, ",") {
+		if field == "" {
+			continue
+		}
+		lo, hi, isRange := strings.Cut(field, "-")
+		first, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, err
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(hi); err != nil {
+				return nil, err
+			}
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
+
+// cpuMask is a kernel cpu_set_t / nodemask_t, 1024 bits
+type cpuMask [16]uint64
+
+func (m *cpuMask) set(bit int) error {
+	if bit < 0 || bit >= len(m)*64 {
+		return fmt.Errorf("cpu or node %d out of mask range", bit)
+	}
+	m[bit/64] |= 1 << (bit % 64)
+	return nil
+}
+
+// pinThread sets the calling thread's affinity to cpus and hands back a
+// function putting the old affinity back, the caller must hold LockOSThread
+func pinThread(cpus []int) (func(), error) {
+	var previous, mask cpuMask
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_GETAFFINITY, 0, unsafe.Sizeof(previous), uintptr(unsafe.Pointer(&previous))); errno != 0 {
+		return nil, errno
+	}
+	for _, cpu := range cpus {
+		if err := mask.set(cpu); err != nil {
+			return nil, err
+		}
+	}
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, 0, unsafe.Sizeof(mask), uintptr(unsafe.Pointer(&mask))); errno != 0 {
+		return nil, errno
+	}
+	return func() {
+		syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, 0, unsafe.Sizeof(previous), uintptr(unsafe.Pointer(&previous)))
+	}, nil
+}
+
+// mbindNode binds mem to node with MPOL_BIND, pages fault in there on first
+// touch and the kernel won't fall back to another node
+func mbindNode(mem []byte, node int) error {
+	const mpolBind = 2
+	var nodes cpuMask
+	if err := nodes.set(node); err != nil {
+		return err
+	}
+	// maxnode counts bits, the kernel drops the last one
+	_, _, errno := syscall.Syscall6(syscall.SYS_MBIND, uintptr(unsafe.Pointer(&mem[0])), uintptr(len(mem)),
+		mpolBind, uintptr(unsafe.Pointer(&nodes)), uintptr(len(nodes)*64+1), 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// procStatusBytes reads a kB field like "VmHWM:" from /proc/self/status
+func procStatusBytes(field string) int64 {
+	status, err := os.ReadFile("/proc/self/status")
+	if err != nil {
+		return -1
+	}
+	for _, line := range strings.Split(string(status), "\n") {
+		if rest, ok := strings.CutPrefix(line, field); ok {
+			kb, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimSpace(rest), " kB"), 10, 64)
+			if err != nil {
+				return -1
+			}
+			return kb * 1024
+		}
+	}
+	return -1
+}
@@ -0,0 +1,34 @@
+//go:build unix
+
+package main
+
+import (
+	"runtime"
+	"syscall"
+)
+
+func init() {
+	anonMapper = pageMapper{"mmap", mmapAnon, syscall.Munmap}
+	// linux reads /proc instead, see memory_linux.go
+	if runtime.GOOS != "linux" {
+		peakRSS = rusagePeakRSS
+	}
+}
+
+// mmapAnon maps private anonymous memory, pages are faulted in on first touch
+func mmapAnon(size int) ([]byte, error) {
+	return syscall.Mmap(-1, 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_PRIVATE|syscall.MAP_ANON)
+}
+
+// rusagePeakRSS is the peak rss since process start, ru_maxrss is bytes on
+// darwin and kilobytes on the other unixes
+func rusagePeakRSS() int64 {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return -1
+	}
+	if runtime.GOOS == "darwin" || runtime.GOOS == "ios" {
+		return int64(usage.Maxrss)
+	}
+	return int64(usage.Maxrss) * 1024
+}
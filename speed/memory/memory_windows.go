@@ -0,0 +1,44 @@
+package main
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var procGetProcessMemoryInfo = syscall.NewLazyDLL("kernel32.dll").NewProc("K32GetProcessMemoryInfo")
+
+// processMemoryCounters is PROCESS_MEMORY_COUNTERS from psapi.h
+type processMemoryCounters struct {
+	cb                         uint32
+	pageFaultCount             uint32
+	peakWorkingSetSize         uintptr
+	workingSetSize             uintptr
+	quotaPeakPagedPoolUsage    uintptr
+	quotaPagedPoolUsage        uintptr
+	quotaPeakNonPagedPoolUsage uintptr
+	quotaNonPagedPoolUsage     uintptr
+	pagefileUsage              uintptr
+	peakPagefileUsage          uintptr
+}
+
+func init() {
+	peakRSS = peakWorkingSet
+}
+
+// peakWorkingSet is the windows peak rss since process start
+func peakWorkingSet() int64 {
+	if procGetProcessMemoryInfo.Find() != nil {
+		return -1
+	}
+	process, err := syscall.GetCurrentProcess()
+	if err != nil {
+		return -1
+	}
+	var counters processMemoryCounters
+	counters.cb = uint32(unsafe.Sizeof(counters))
+	ok, _, _ := procGetProcessMemoryInfo.Call(uintptr(process), uintptr(unsafe.Pointer(&counters)), uintptr(counters.cb))
+	if ok == 0 {
+		return -1
+	}
+	return int64(counters.peakWorkingSetSize)
+}